@@ -0,0 +1,45 @@
+package libvirt_schema
+
+// Capabilities is the host part of the ConnectGetCapabilities XML.
+type Capabilities struct {
+	Host CapsHost `xml:"host"`
+}
+
+type CapsHost struct {
+	CPU      CapsCPU      `xml:"cpu"`
+	Topology CapsTopology `xml:"topology"`
+}
+
+type CapsCPU struct {
+	Arch     string          `xml:"arch"`
+	Model    string          `xml:"model"`
+	Vendor   string          `xml:"vendor"`
+	Topology CapsCPUTopology `xml:"topology"`
+}
+
+type CapsCPUTopology struct {
+	Sockets uint `xml:"sockets,attr"`
+	Dies    uint `xml:"dies,attr"`
+	Cores   uint `xml:"cores,attr"`
+	Threads uint `xml:"threads,attr"`
+}
+
+type CapsTopology struct {
+	Cells []CapsCell `xml:"cells>cell"`
+}
+
+// CapsCell is a NUMA cell of the host.
+type CapsCell struct {
+	ID     uint           `xml:"id,attr"`
+	Memory CapsCellMemory `xml:"memory"`
+	CPUs   []CapsCellCPU  `xml:"cpus>cpu"`
+}
+
+type CapsCellMemory struct {
+	Unit  string `xml:"unit,attr"`
+	Value uint64 `xml:",chardata"`
+}
+
+type CapsCellCPU struct {
+	ID uint `xml:"id,attr"`
+}
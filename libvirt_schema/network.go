@@ -0,0 +1,27 @@
+package libvirt_schema
+
+// Network is the part of the network XML the exporter uses.
+type Network struct {
+	Name   string        `xml:"name"`
+	Bridge NetworkBridge `xml:"bridge"`
+	IPs    []NetworkIP   `xml:"ip"`
+}
+
+type NetworkBridge struct {
+	Name string `xml:"name,attr"`
+}
+
+type NetworkIP struct {
+	Family  string      `xml:"family,attr"`
+	Address string      `xml:"address,attr"`
+	DHCP    NetworkDHCP `xml:"dhcp"`
+}
+
+type NetworkDHCP struct {
+	Ranges []NetworkDHCPRange `xml:"range"`
+}
+
+type NetworkDHCPRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
@@ -12,6 +12,40 @@ type Domain struct {
 
 type Metadata struct {
 	NovaInstance NovaInstance `xml:"instance"`
+	KubeVirt     KubeVirt     `xml:"http://kubevirt.io kubevirt"`
+	OVirtVM      OVirtVM      `xml:"http://ovirt.org/vm/1.0 vm"`
+	OVirtTune    OVirtTune    `xml:"http://ovirt.org/vm/tune/1.0 qos"`
+}
+
+// KubeVirt is the metadata virt-launcher adds to the domains of VMIs. The
+// namespace and name of the VMI are only in the domain name, as
+// <namespace>_<name>.
+type KubeVirt struct {
+	UID string `xml:"uid"`
+}
+
+// OVirtVM is the metadata vdsm adds to the domains of oVirt/RHV VMs, whose
+// UUID is the oVirt VM ID.
+type OVirtVM struct {
+	XMLName        xml.Name      `xml:"http://ovirt.org/vm/1.0 vm"`
+	ClusterVersion string        `xml:"clusterVersion"`
+	Devices        []OVirtDevice `xml:"device"`
+}
+
+// OVirtTune is the QoS vdsm applies to the domains of oVirt/RHV VMs. The
+// vCPU limit is in percent of the vCPUs and only set with a CPU QoS profile.
+type OVirtTune struct {
+	VCPULimit *uint64 `xml:"vcpuLimit"`
+}
+
+// OVirtDevice maps a device of the domain to its oVirt storage.
+type OVirtDevice struct {
+	DevType  string `xml:"devtype,attr"`
+	Name     string `xml:"name,attr"`
+	DomainID string `xml:"domainID"`
+	PoolID   string `xml:"poolID"`
+	ImageID  string `xml:"imageID"`
+	VolumeID string `xml:"volumeID"`
 }
 
 type OSMetadata struct {
@@ -25,10 +59,17 @@ type OSType struct {
 }
 
 type NovaInstance struct {
-	XMLName xml.Name   `xml:"instance"`
-	Name    string     `xml:"name"`
-	Owner   NovaOwner  `xml:"owner"`
-	Flavor  NovaFlavor `xml:"flavor"`
+	XMLName      xml.Name    `xml:"instance"`
+	Package      NovaPackage `xml:"package"`
+	Name         string      `xml:"name"`
+	CreationTime string      `xml:"creationTime"`
+	Owner        NovaOwner   `xml:"owner"`
+	Flavor       NovaFlavor  `xml:"flavor"`
+	Root         NovaRoot    `xml:"root"`
+}
+
+type NovaPackage struct {
+	Version string `xml:"version,attr"`
 }
 
 type NovaOwner struct {
@@ -47,8 +88,21 @@ type NovaProject struct {
 	ProjectName string `xml:",chardata"`
 }
 
+// NovaFlavor holds the flavor sizes in MiB (memory, swap) and GiB (disk,
+// ephemeral).
 type NovaFlavor struct {
 	FlavorName string `xml:"name,attr"`
+	Memory     uint64 `xml:"memory"`
+	Disk       uint64 `xml:"disk"`
+	Swap       uint64 `xml:"swap"`
+	Ephemeral  uint64 `xml:"ephemeral"`
+	VCPUs      uint64 `xml:"vcpus"`
+}
+
+// NovaRoot is the root disk of the instance, an image or a volume.
+type NovaRoot struct {
+	Type string `xml:"type,attr"`
+	UUID string `xml:"uuid,attr"`
 }
 
 type Devices struct {
@@ -74,9 +128,18 @@ type DiskDriver struct {
 
 type DiskSource struct {
 	File     string `xml:"file,attr"`
+	Dev      string `xml:"dev,attr"`
 	Protocol string `xml:"protocol,attr"`
 }
 
+// Path returns the file of file disks or the device of block disks.
+func (s DiskSource) Path() string {
+	if s.File != "" {
+		return s.File
+	}
+	return s.Dev
+}
+
 type DiskTarget struct {
 	Device string `xml:"dev,attr"`
 	Bus    string `xml:"bus,attr"`
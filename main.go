@@ -2,36 +2,96 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
 	"github.com/digitalocean/go-libvirt"
 	"github.com/go-kit/log/level"
-	exporter "github.com/inovex/prometheus-libvirt-exporter/pkg/exporter"
 	"github.com/prometheus/client_golang/prometheus"
+	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
+	"github.com/prometheus/common/promslog"
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/exporter-toolkit/web"
 	webflag "github.com/prometheus/exporter-toolkit/web/kingpinflag"
+	exporter "github.com/thongth1998/libvirt-exporter/pkg/exporter"
 )
 
 func main() {
 
 	var (
 		libvirtURI = kingpin.Flag("libvirt.uri",
-			"Libvirt URI from which to extract metrics.",
+			"Libvirt URI from which to extract metrics. Either a local socket path or a full URI such as qemu+tcp://host/system or qemu+tls://host/system.",
 		).Default("/var/run/libvirt/libvirt-sock-ro").String()
 		driver = kingpin.Flag("libvirt.driver",
-			fmt.Sprintf("Available drivers: %s (Default), %s, %s and %s ", libvirt.QEMUSystem, libvirt.QEMUSession, libvirt.XenSystem, libvirt.TestDefault),
+			fmt.Sprintf("Driver used with a local socket path. Available drivers: %s (Default), %s, %s and %s ", libvirt.QEMUSystem, libvirt.QEMUSession, libvirt.XenSystem, libvirt.TestDefault),
 		).Default(string(libvirt.QEMUSystem)).String()
+		tlsCertFile = kingpin.Flag("libvirt.tls-cert-file",
+			"Client certificate for qemu+tls:// URIs. Defaults to libvirt's /etc/pki/libvirt/clientcert.pem.",
+		).String()
+		tlsKeyFile = kingpin.Flag("libvirt.tls-key-file",
+			"Client key for qemu+tls:// URIs. Defaults to libvirt's /etc/pki/libvirt/private/clientkey.pem.",
+		).String()
+		tlsCAFile = kingpin.Flag("libvirt.tls-ca-file",
+			"CA certificate for qemu+tls:// URIs. Defaults to libvirt's /etc/pki/CA/cacert.pem.",
+		).String()
+		tlsInsecureSkipVerify = kingpin.Flag("libvirt.tls-insecure-skip-verify",
+			"Do not verify the libvirtd server certificate.",
+		).Bool()
+		keepaliveInterval = kingpin.Flag("libvirt.keepalive-interval",
+			"How often the libvirt connection is checked for liveness.",
+		).Default("5s").Duration()
+		reconnectBackoffMin = kingpin.Flag("libvirt.reconnect-backoff-min",
+			"Initial delay before reconnecting to libvirt after the connection was lost.",
+		).Default("1s").Duration()
+		reconnectBackoffMax = kingpin.Flag("libvirt.reconnect-backoff-max",
+			"Maximum delay between libvirt reconnect attempts.",
+		).Default("1m").Duration()
+		workers = kingpin.Flag("libvirt.workers",
+			"Number of domains collected concurrently.",
+		).Default("4").Int()
+		scrapeTimeout = kingpin.Flag("libvirt.scrape-timeout",
+			"Deadline for collecting all domains when Prometheus does not send a scrape timeout header. 0 disables it.",
+		).Default("0s").Duration()
+		timeoutOffset = kingpin.Flag("web.timeout-offset",
+			"Time subtracted from the Prometheus scrape timeout to leave room for sending the response.",
+		).Default("500ms").Duration()
+		pollInterval = kingpin.Flag("libvirt.poll-interval",
+			"Collect libvirt in the background at this interval and serve scrapes from the latest result. 0 collects on every scrape.",
+		).Default("0s").Duration()
+		lifecycleEvents = kingpin.Flag("libvirt.lifecycle-events",
+			"Subscribe to domain lifecycle events and count them in libvirt_domain_lifecycle_events_total.",
+		).Default("true").Bool()
+		domainCacheTTL = kingpin.Flag("libvirt.domain-cache-ttl",
+			"How long the parsed XML of a domain is cached. Domain events invalidate entries earlier. 0 disables the cache.",
+		).Default("5m").Duration()
+		sampleInterval = kingpin.Flag("libvirt.sample-interval",
+			"Sample the counters the *_percent metrics are derived from at this interval. 0 disables the percent metrics.",
+		).Default("15s").Duration()
+		rateWindows = kingpin.Flag("libvirt.rate-window",
+			"Window the *_percent metrics are computed over. Repeat for several windows.",
+		).Default("1m", "5m").DurationList()
+		bulkStats = kingpin.Flag("libvirt.bulk-stats",
+			"Fetch the stats of all domains with a single ConnectGetAllDomainStats call instead of per-domain calls.",
+		).Default("true").Bool()
 	)
 
 	metricsPath := kingpin.Flag(
 		"web.telemetry-path", "Path under which to expose metrics",
 	).Default("/metrics").String()
+	probePath := kingpin.Flag(
+		"web.probe-path", "Path under which to expose multi-target probe metrics",
+	).Default("/probe").String()
+	configFile := kingpin.Flag(
+		"config.file", "Path to the YAML configuration file, reloaded on SIGHUP or a POST to /-/reload",
+	).String()
 	toolkitFlags := webflag.AddFlags(kingpin.CommandLine, ":9188")
 
 	promlogConfig := &promlog.Config{}
@@ -44,13 +104,53 @@ func main() {
 	_ = level.Info(logger).Log("msg", "Starting libvirt_exporter", "version", version.Info())
 	_ = level.Info(logger).Log("msg", "Build context", "build_context", version.BuildContext())
 
-	exporter, err := exporter.NewLibvirtExporter(*libvirtURI, libvirt.ConnectURI(*driver), logger)
+	// the configuration file overrides the flags and is applied again on
+	// every reload
+	build := func(config *exporter.Config) (*exporter.LibvirtExporter, error) {
+		uri, connectURI := config.Target(*libvirtURI, libvirt.ConnectURI(*driver))
+		opts := []exporter.Option{
+			exporter.WithKeepaliveInterval(*keepaliveInterval),
+			exporter.WithReconnectBackoff(*reconnectBackoffMin, *reconnectBackoffMax),
+			exporter.WithBulkStats(*bulkStats),
+			exporter.WithWorkers(*workers),
+			exporter.WithScrapeTimeout(*scrapeTimeout),
+			exporter.WithPollInterval(*pollInterval),
+			exporter.WithLifecycleEvents(*lifecycleEvents),
+			exporter.WithDomainCacheTTL(*domainCacheTTL),
+			exporter.WithRateWindows(*sampleInterval, *rateWindows...),
+			exporter.WithTLSConfig(exporter.TLSConfig{
+				CertFile:           *tlsCertFile,
+				KeyFile:            *tlsKeyFile,
+				CAFile:             *tlsCAFile,
+				InsecureSkipVerify: *tlsInsecureSkipVerify,
+			}),
+		}
+		return exporter.NewLibvirtExporter(uri, connectURI, logger, append(opts, config.Options()...)...)
+	}
+	reloader, err := exporter.NewReloader(*configFile, build, logger)
 	if err != nil {
-		panic(err)
+		_ = level.Error(logger).Log("msg", "Error loading config", "err", err)
+		os.Exit(1)
 	}
-	prometheus.MustRegister(exporter)
+	defer reloader.Close()
+	prometheus.MustRegister(reloader)
+	prometheus.MustRegister(versioncollector.NewCollector("libvirt_exporter"))
 
-	http.Handle(*metricsPath, promhttp.Handler())
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloader.Reload(); err != nil {
+				_ = level.Error(logger).Log("msg", "Error reloading config", "err", err)
+			}
+		}
+	}()
+
+	http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer, reloader.Handler(prometheus.DefaultGatherer, *timeoutOffset),
+	))
+	http.Handle(*probePath, reloader.ProbeHandler(*timeoutOffset))
+	http.Handle("/-/reload", reloader.ReloadHandler())
 	if *metricsPath != "/" {
 		landingCnf := web.LandingConfig{
 			Name:        "Libvirt Exporter",
@@ -61,6 +161,10 @@ func main() {
 					Address: *metricsPath,
 					Text:    "Metrics",
 				},
+				{
+					Address: *probePath + "?target=" + url.QueryEscape(*libvirtURI),
+					Text:    "Probe local libvirt",
+				},
 			},
 		}
 		landingPage, err := web.NewLandingPage(landingCnf)
@@ -72,8 +176,23 @@ func main() {
 	}
 
 	srv := &http.Server{}
-	if err = web.ListenAndServe(srv, toolkitFlags, logger); err != nil {
+	if err = web.ListenAndServe(srv, toolkitFlags, webLogger(promlogConfig)); err != nil {
 		_ = level.Error(logger).Log("err", err)
 		os.Exit(1)
 	}
 }
+
+// webLogger returns a slog logger for the exporter toolkit that honours the
+// configured log level and format.
+func webLogger(cfg *promlog.Config) *slog.Logger {
+	slogConfig := &promslog.Config{}
+	if cfg.Level != nil {
+		slogConfig.Level = &promslog.AllowedLevel{}
+		_ = slogConfig.Level.Set(cfg.Level.String())
+	}
+	if cfg.Format != nil {
+		slogConfig.Format = &promslog.AllowedFormat{}
+		_ = slogConfig.Format.Set(cfg.Format.String())
+	}
+	return promslog.New(slogConfig)
+}
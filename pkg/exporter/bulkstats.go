@@ -0,0 +1,213 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+// bulkStatsGroups are the ConnectGetAllDomainStats groups that replace the
+// per-domain DomainGetInfo, DomainBlockStats, DomainInterfaceStats,
+// DomainMemoryStats and vCPU calls.
+const bulkStatsGroups = libvirt.DomainStatsState |
+	libvirt.DomainStatsCPUTotal |
+	libvirt.DomainStatsBalloon |
+	libvirt.DomainStatsVCPU |
+	libvirt.DomainStatsInterface |
+	libvirt.DomainStatsBlock
+
+// domainStats holds one ConnectGetAllDomainStats record of a domain.
+type domainStats struct {
+	params  []libvirt.TypedParam
+	byField map[string]libvirt.TypedParamValue
+}
+
+func newDomainStats(params []libvirt.TypedParam) *domainStats {
+	s := &domainStats{
+		params:  params,
+		byField: make(map[string]libvirt.TypedParamValue, len(params)),
+	}
+	for _, param := range params {
+		s.byField[param.Field] = param.Value
+	}
+	return s
+}
+
+// uint returns the numeric value of field, whatever integer type libvirt used for it.
+func (s *domainStats) uint(field string) (uint64, bool) {
+	v, ok := s.byField[field]
+	if !ok {
+		return 0, false
+	}
+	switch i := v.I.(type) {
+	case int32:
+		return uint64(i), true
+	case uint32:
+		return uint64(i), true
+	case int64:
+		return uint64(i), true
+	case uint64:
+		return i, true
+	case float64:
+		return uint64(i), true
+	}
+	return 0, false
+}
+
+func (s *domainStats) str(field string) string {
+	if v, ok := s.byField[field].I.(string); ok {
+		return v
+	}
+	return ""
+}
+
+// domainInfo returns the DomainGetInfo equivalent of the state, balloon,
+// vcpu and cpu-total groups.
+func (s *domainStats) domainInfo() (state uint8, maxMem, memory uint64, nrVirtCPU uint16, cpuTime uint64) {
+	st, _ := s.uint("state.state")
+	maxMem, _ = s.uint("balloon.maximum")
+	memory, _ = s.uint("balloon.current")
+	vcpus, _ := s.uint("vcpu.current")
+	cpuTime, _ = s.uint("cpu.time")
+	return uint8(st), maxMem, memory, uint16(vcpus), cpuTime
+}
+
+// isActive reports whether the domain is running, paused or otherwise alive.
+func (s *domainStats) isActive() bool {
+	st, _ := s.uint("state.state")
+	switch libvirt_schema.DomainState(st) {
+	case libvirt_schema.DOMAIN_NOSTATE, libvirt_schema.DOMAIN_SHUTOFF:
+		return false
+	}
+	return true
+}
+
+// indexByName maps the name of every "<group>.<n>.name" entry to n.
+func (s *domainStats) indexByName(group string) map[string]string {
+	count, _ := s.uint(group + ".count")
+	names := make(map[string]string, count)
+	for i := uint64(0); i < count; i++ {
+		idx := strconv.FormatUint(i, 10)
+		if name := s.str(group + "." + idx + ".name"); name != "" {
+			names[name] = idx
+		}
+	}
+	return names
+}
+
+// blockStats returns the DomainBlockStats equivalent of the block group for
+// the given target device.
+func (s *domainStats) blockStats(device string) (rdReq, rdBytes, wrReq, wrBytes int64, err error) {
+	idx, ok := s.indexByName("block")[device]
+	if !ok {
+		return 0, 0, 0, 0, fmt.Errorf("no bulk block stats for device %s", device)
+	}
+	prefix := "block." + idx + "."
+	v, _ := s.uint(prefix + "rd.reqs")
+	rdReq = int64(v)
+	v, _ = s.uint(prefix + "rd.bytes")
+	rdBytes = int64(v)
+	v, _ = s.uint(prefix + "wr.reqs")
+	wrReq = int64(v)
+	v, _ = s.uint(prefix + "wr.bytes")
+	wrBytes = int64(v)
+	return
+}
+
+// blockExtra returns the capacity and the total read/write times in
+// nanoseconds of the given target device.
+func (s *domainStats) blockExtra(device string) (capacity uint64, rdTimes, wrTimes int64) {
+	idx, ok := s.indexByName("block")[device]
+	if !ok {
+		return
+	}
+	prefix := "block." + idx + "."
+	capacity, _ = s.uint(prefix + "capacity")
+	v, _ := s.uint(prefix + "rd.times")
+	rdTimes = int64(v)
+	v, _ = s.uint(prefix + "wr.times")
+	wrTimes = int64(v)
+	return
+}
+
+// interfaceStats returns the DomainInterfaceStats equivalent of the net
+// group for the given target device.
+func (s *domainStats) interfaceStats(device string) (rxBytes, rxPackets, rxErrs, rxDrop, txBytes, txPackets, txErrs, txDrop int64, err error) {
+	idx, ok := s.indexByName("net")[device]
+	if !ok {
+		err = fmt.Errorf("no bulk interface stats for device %s", device)
+		return
+	}
+	prefix := "net." + idx + "."
+	get := func(field string) int64 {
+		v, _ := s.uint(prefix + field)
+		return int64(v)
+	}
+	return get("rx.bytes"), get("rx.pkts"), get("rx.errs"), get("rx.drop"),
+		get("tx.bytes"), get("tx.pkts"), get("tx.errs"), get("tx.drop"), nil
+}
+
+// memoryStats returns the DomainMemoryStats equivalent of the balloon group.
+func (s *domainStats) memoryStats() []libvirt.DomainMemoryStat {
+	fields := []struct {
+		field string
+		tag   libvirt.DomainMemoryStatTags
+	}{
+		{"balloon.swap_in", libvirt.DomainMemoryStatSwapIn},
+		{"balloon.swap_out", libvirt.DomainMemoryStatSwapOut},
+		{"balloon.major_fault", libvirt.DomainMemoryStatMajorFault},
+		{"balloon.minor_fault", libvirt.DomainMemoryStatMinorFault},
+		{"balloon.unused", libvirt.DomainMemoryStatUnused},
+		{"balloon.available", libvirt.DomainMemoryStatAvailable},
+		{"balloon.current", libvirt.DomainMemoryStatActualBalloon},
+		{"balloon.rss", libvirt.DomainMemoryStatRss},
+		{"balloon.usable", libvirt.DomainMemoryStatUsable},
+		{"balloon.last-update", libvirt.DomainMemoryStatLastUpdate},
+		{"balloon.disk_caches", libvirt.DomainMemoryStatDiskCaches},
+	}
+
+	var stats []libvirt.DomainMemoryStat
+	for _, f := range fields {
+		if v, ok := s.uint(f.field); ok {
+			stats = append(stats, libvirt.DomainMemoryStat{Tag: int32(f.tag), Val: v})
+		}
+	}
+	return stats
+}
+
+// vcpuParams returns the vcpu.* parameters of the record.
+func (s *domainStats) vcpuParams() []libvirt.TypedParam {
+	var params []libvirt.TypedParam
+	for _, param := range s.params {
+		if strings.HasPrefix(param.Field, "vcpu.") {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// fetchBulkStats retrieves the stats of all domains with a single
+// ConnectGetAllDomainStats call and attaches each record to its domain.
+func fetchBulkStats(l *libvirt.Libvirt, domains []domainMeta) error {
+	libvirtDomains := make([]libvirt.Domain, 0, len(domains))
+	for _, domain := range domains {
+		libvirtDomains = append(libvirtDomains, domain.libvirtDomain)
+	}
+
+	records, err := l.ConnectGetAllDomainStats(libvirtDomains, uint32(bulkStatsGroups), 0)
+	if err != nil {
+		return err
+	}
+
+	byUUID := make(map[libvirt.UUID]*domainStats, len(records))
+	for _, record := range records {
+		byUUID[record.Dom.UUID] = newDomainStats(record.Params)
+	}
+	for idx := range domains {
+		domains[idx].stats = byUUID[domains[idx].libvirtDomain.UUID]
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainStats(t *testing.T) {
+	param := func(field string, v interface{}) libvirt.TypedParam {
+		return libvirt.TypedParam{Field: field, Value: libvirt.TypedParamValue{I: v}}
+	}
+	stats := newDomainStats([]libvirt.TypedParam{
+		param("state.state", int32(1)),
+		param("cpu.time", uint64(5e9)),
+		param("balloon.current", uint64(1048576)),
+		param("balloon.maximum", uint64(2097152)),
+		param("balloon.unused", uint64(524288)),
+		param("vcpu.current", uint32(2)),
+		param("vcpu.maximum", uint32(4)),
+		param("vcpu.0.time", uint64(1e9)),
+		param("net.count", uint32(1)),
+		param("net.0.name", "tap0"),
+		param("net.0.rx.bytes", uint64(100)),
+		param("net.0.tx.drop", uint64(3)),
+		param("block.count", uint32(2)),
+		param("block.0.name", "vda"),
+		param("block.0.rd.reqs", uint64(10)),
+		param("block.0.capacity", uint64(1<<30)),
+		param("block.1.name", "vdb"),
+		param("block.1.wr.bytes", uint64(4096)),
+		param("block.1.wr.times", uint64(7)),
+	})
+
+	state, maxMem, memory, vcpus, cpuTime := stats.domainInfo()
+	assert.Equal(t, uint8(1), state)
+	assert.Equal(t, uint64(2097152), maxMem)
+	assert.Equal(t, uint64(1048576), memory)
+	assert.Equal(t, uint16(2), vcpus)
+	assert.Equal(t, uint64(5e9), cpuTime)
+	assert.True(t, stats.isActive())
+
+	rdReq, _, _, _, err := stats.blockStats("vda")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), rdReq)
+	_, _, _, wrBytes, err := stats.blockStats("vdb")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4096), wrBytes)
+	_, _, _, _, err = stats.blockStats("vdc")
+	assert.Error(t, err)
+
+	capacity, _, wrTimes := stats.blockExtra("vdb")
+	assert.Equal(t, uint64(0), capacity)
+	assert.Equal(t, int64(7), wrTimes)
+	capacity, _, _ = stats.blockExtra("vda")
+	assert.Equal(t, uint64(1<<30), capacity)
+
+	rxBytes, _, _, _, _, _, _, txDrop, err := stats.interfaceStats("tap0")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), rxBytes)
+	assert.Equal(t, int64(3), txDrop)
+
+	assert.Contains(t, stats.memoryStats(), libvirt.DomainMemoryStat{Tag: int32(libvirt.DomainMemoryStatUnused), Val: 524288})
+	assert.Len(t, stats.vcpuParams(), 3)
+}
@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"strconv"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+var (
+	libvirtNodeCPUCapsInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpu_info"),
+		"Architecture, model and vendor of the host CPU from the capabilities as labels.",
+		[]string{"arch", "model", "vendor"},
+		nil)
+	libvirtNodeNUMACellMemoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "numa_cell_memory_total_bytes"),
+		"Memory of a NUMA cell of the host in bytes.",
+		[]string{"cell"},
+		nil)
+	libvirtNodeNUMACellCPUsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "numa_cell_cpus"),
+		"Number of CPUs of a NUMA cell of the host.",
+		[]string{"cell"},
+		nil)
+	libvirtNodeVCPUOvercommitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "vcpu_overcommit_ratio"),
+		"Virtual CPUs of the running or all defined domains over the CPUs of the host.",
+		[]string{"domains"},
+		nil)
+	libvirtNodeMemoryOvercommitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "memory_overcommit_ratio"),
+		"Maximum memory of the running or all defined domains over the memory of the host.",
+		[]string{"domains"},
+		nil)
+)
+
+func init() {
+	registerCollector(&collector{
+		name: "capabilities",
+		descs: []*prometheus.Desc{
+			libvirtNodeCPUCapsInfoDesc,
+			libvirtNodeNUMACellMemoryDesc,
+			libvirtNodeNUMACellCPUsDesc,
+			libvirtNodeVCPUOvercommitDesc,
+			libvirtNodeMemoryOvercommitDesc,
+		},
+		host: CollectCapabilities,
+	}, true)
+}
+
+// domainResources sums the virtual CPUs and maximum memory in KiB of
+// domains.
+type domainResources struct {
+	vcpus     uint64
+	maxMemory uint64
+}
+
+// hostResources returns the number of CPUs and the memory in KiB of the host.
+// Without NUMA topology the CPUs are counted from the CPU topology and the
+// memory is unknown.
+func hostResources(caps libvirt_schema.Capabilities) (cpus, memory uint64) {
+	for _, cell := range caps.Host.Topology.Cells {
+		cpus += uint64(len(cell.CPUs))
+		// the capabilities give the memory in KiB
+		memory += cell.Memory.Value
+	}
+	if cpus == 0 {
+		t := caps.Host.CPU.Topology
+		dies := t.Dies
+		if dies == 0 {
+			dies = 1
+		}
+		cpus = uint64(t.Sockets * dies * t.Cores * t.Threads)
+	}
+	return cpus, memory
+}
+
+// CollectCapabilities collects the host topology of ConnectGetCapabilities
+// and how much the domains overcommit it.
+func CollectCapabilities(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error) {
+	capsXML, err := l.ConnectGetCapabilities()
+	if err != nil {
+		_ = level.Error(logger).Log("err", "failed to get ConnectGetCapabilities", "msg", err)
+		return err
+	}
+	var caps libvirt_schema.Capabilities
+	if err = xml.Unmarshal([]byte(capsXML), &caps); err != nil {
+		_ = level.Error(logger).Log("err", "failed to unmarshal capabilities", "msg", err)
+		return err
+	}
+
+	cpu := caps.Host.CPU
+	// the topology is exported by the node collector
+	ch <- prometheus.MustNewConstMetric(libvirtNodeCPUCapsInfoDesc, prometheus.GaugeValue, 1, cpu.Arch, cpu.Model, cpu.Vendor)
+	for _, cell := range caps.Host.Topology.Cells {
+		id := strconv.FormatUint(uint64(cell.ID), 10)
+		ch <- prometheus.MustNewConstMetric(libvirtNodeNUMACellMemoryDesc, prometheus.GaugeValue, float64(cell.Memory.Value)*1024, id)
+		ch <- prometheus.MustNewConstMetric(libvirtNodeNUMACellCPUsDesc, prometheus.GaugeValue, float64(len(cell.CPUs)), id)
+	}
+
+	running, defined, err := sumDomainResources(l, logger)
+	if err != nil {
+		return err
+	}
+
+	cpus, memory := hostResources(caps)
+	for _, d := range []struct {
+		name      string
+		resources domainResources
+	}{{"running", running}, {"defined", defined}} {
+		if cpus > 0 {
+			ch <- prometheus.MustNewConstMetric(libvirtNodeVCPUOvercommitDesc, prometheus.GaugeValue, float64(d.resources.vcpus)/float64(cpus), d.name)
+		}
+		if memory > 0 {
+			ch <- prometheus.MustNewConstMetric(libvirtNodeMemoryOvercommitDesc, prometheus.GaugeValue, float64(d.resources.maxMemory)/float64(memory), d.name)
+		}
+	}
+	return nil
+}
+
+// capabilitiesStatsGroups are the ConnectGetAllDomainStats groups with the
+// virtual CPUs and maximum memory of a domain.
+const capabilitiesStatsGroups = libvirt.DomainStatsState |
+	libvirt.DomainStatsBalloon |
+	libvirt.DomainStatsVCPU
+
+// sumDomainResources sums the resources of the running and of all defined
+// domains, listed with one ConnectGetAllDomainStats call so that no domain is
+// counted twice.
+func sumDomainResources(l *libvirt.Libvirt, logger log.Logger) (running, defined domainResources, err error) {
+	records, err := l.ConnectGetAllDomainStats(nil, uint32(capabilitiesStatsGroups),
+		uint32(libvirt.ConnectGetAllDomainsStatsActive|libvirt.ConnectGetAllDomainsStatsInactive))
+	if err != nil {
+		_ = level.Error(logger).Log("err", "failed to get ConnectGetAllDomainStats", "msg", err)
+		return running, defined, err
+	}
+	stats := make([]*domainStats, 0, len(records))
+	for _, record := range records {
+		stats = append(stats, newDomainStats(record.Params))
+	}
+	running, defined = sumDomainStats(stats)
+	return running, defined, nil
+}
+
+// sumDomainStats sums the virtual CPUs and maximum memory of the running
+// domains and of all domains.
+func sumDomainStats(stats []*domainStats) (running, defined domainResources) {
+	for _, s := range stats {
+		_, maxMem, _, nrVirtCPU, _ := s.domainInfo()
+		defined.vcpus += uint64(nrVirtCPU)
+		defined.maxMemory += maxMem
+		if s.isActive() {
+			running.vcpus += uint64(nrVirtCPU)
+			running.maxMemory += maxMem
+		}
+	}
+	return running, defined
+}
@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+func TestHostResources(t *testing.T) {
+	var caps libvirt_schema.Capabilities
+	require.NoError(t, xml.Unmarshal([]byte(`<capabilities>
+  <host>
+    <uuid>4c4c4544-0042-3510-8052-b4c04f4e4d32</uuid>
+    <cpu>
+      <arch>x86_64</arch>
+      <model>Skylake-Client-IBRS</model>
+      <vendor>Intel</vendor>
+      <topology sockets='1' dies='1' cores='2' threads='2'/>
+    </cpu>
+    <topology>
+      <cells num='2'>
+        <cell id='0'>
+          <memory unit='KiB'>8388608</memory>
+          <cpus num='2'>
+            <cpu id='0' socket_id='0' die_id='0' core_id='0' siblings='0,2'/>
+            <cpu id='2' socket_id='0' die_id='0' core_id='0' siblings='0,2'/>
+          </cpus>
+        </cell>
+        <cell id='1'>
+          <memory unit='KiB'>8388608</memory>
+          <cpus num='2'>
+            <cpu id='1' socket_id='0' die_id='0' core_id='1' siblings='1,3'/>
+            <cpu id='3' socket_id='0' die_id='0' core_id='1' siblings='1,3'/>
+          </cpus>
+        </cell>
+      </cells>
+    </topology>
+  </host>
+</capabilities>`), &caps))
+
+	assert.Equal(t, "Skylake-Client-IBRS", caps.Host.CPU.Model)
+	cpus, memory := hostResources(caps)
+	assert.Equal(t, uint64(4), cpus)
+	assert.Equal(t, uint64(16777216), memory)
+
+	// without NUMA topology only the CPUs are known
+	caps.Host.Topology.Cells = nil
+	caps.Host.CPU.Topology.Dies = 0
+	cpus, memory = hostResources(caps)
+	assert.Equal(t, uint64(4), cpus)
+	assert.Zero(t, memory)
+}
+
+func TestSumDomainStats(t *testing.T) {
+	domain := func(state int32, vcpus uint32, maxMem uint64) *domainStats {
+		return newDomainStats([]libvirt.TypedParam{
+			{Field: "state.state", Value: libvirt.TypedParamValue{D: 1, I: state}},
+			{Field: "vcpu.current", Value: libvirt.TypedParamValue{D: 3, I: vcpus}},
+			{Field: "balloon.maximum", Value: libvirt.TypedParamValue{D: 4, I: maxMem}},
+		})
+	}
+	running, defined := sumDomainStats([]*domainStats{
+		domain(1, 2, 4194304), // running
+		domain(3, 4, 8388608), // paused
+		domain(5, 8, 2097152), // shut off
+	})
+	assert.Equal(t, domainResources{vcpus: 6, maxMemory: 12582912}, running)
+	assert.Equal(t, domainResources{vcpus: 14, maxMemory: 14680064}, defined)
+}
@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hostCollectFunc collects metrics that are not tied to a single domain,
+// once per scrape.
+type hostCollectFunc func(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error)
+
+// collector is a named group of metrics that can be switched on and off
+// with --collector.<name> and --no-collector.<name>. Exactly one of domain
+// and host is set, with domainDescs and descs respectively.
+type collector struct {
+	name        string
+	domainDescs []*domainDesc
+	descs       []*prometheus.Desc
+
+	// domain is run for every active domain.
+	domain collectFunc
+	// host is run once per scrape.
+	host hostCollectFunc
+}
+
+var (
+	collectors     = make(map[string]*collector)
+	collectorState = make(map[string]*bool)
+	// collectorFlagged records the collectors whose flag was given explicitly,
+	// so --collector.disable-defaults leaves them alone.
+	collectorFlagged = make(map[string]bool)
+
+	disableDefaultCollectors = kingpin.Flag(
+		"collector.disable-defaults",
+		"Set all collectors to disabled by default.",
+	).Default("false").Bool()
+)
+
+// registerCollector adds a collector and its enable flag. It is meant to be
+// called from init functions.
+func registerCollector(c *collector, defaultEnabled bool) {
+	if _, ok := collectors[c.name]; ok {
+		panic(fmt.Sprintf("collector %q registered twice", c.name))
+	}
+	if (c.domain == nil) == (c.host == nil) {
+		panic(fmt.Sprintf("collector %q must collect either per domain or per host", c.name))
+	}
+
+	defaultValue := "false"
+	help := fmt.Sprintf("Enable the %s collector (default: disabled).", c.name)
+	if defaultEnabled {
+		defaultValue = "true"
+		help = fmt.Sprintf("Enable the %s collector (default: enabled).", c.name)
+	}
+
+	// start out with the default, so the state is right even if the flags
+	// are never parsed
+	name, enabled := c.name, defaultEnabled
+	collectors[name] = c
+	collectorState[name] = &enabled
+	kingpin.Flag("collector."+name, help).
+		Default(defaultValue).
+		Action(func(*kingpin.ParseContext) error {
+			collectorFlagged[name] = true
+			return nil
+		}).
+		BoolVar(&enabled)
+}
+
+// collectorNames returns the names of all registered collectors, sorted.
+func collectorNames() []string {
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// enabledCollectors returns the names of the collectors enabled by flags.
+func enabledCollectors() []string {
+	var names []string
+	for _, name := range collectorNames() {
+		if *disableDefaultCollectors && !collectorFlagged[name] {
+			continue
+		}
+		if *collectorState[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// collectorSet is the set of collectors used by one exporter, in the order
+// of their names.
+type collectorSet []*collector
+
+func newCollectorSet(names []string) (collectorSet, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := collectors[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+		wanted[name] = true
+	}
+
+	var set collectorSet
+	for _, name := range collectorNames() {
+		if wanted[name] {
+			set = append(set, collectors[name])
+		}
+	}
+	return set, nil
+}
+
+func (s collectorSet) describe(ch chan<- *prometheus.Desc, labels *labelSchema) {
+	for _, c := range s {
+		labels.describe(ch, c.domainDescs...)
+		for _, desc := range c.descs {
+			ch <- desc
+		}
+	}
+}
+
+func (s collectorSet) domainCollectors() []*collector {
+	var domain []*collector
+	for _, c := range s {
+		if c.domain != nil {
+			domain = append(domain, c)
+		}
+	}
+	return domain
+}
+
+func (s collectorSet) hostCollectors() []*collector {
+	var host []*collector
+	for _, c := range s {
+		if c.host != nil {
+			host = append(host, c)
+		}
+	}
+	return host
+}
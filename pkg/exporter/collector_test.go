@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorSet(t *testing.T) {
+	set, err := newCollectorSet([]string{"vcpu", "storagepool", "block"})
+	require.NoError(t, err)
+	require.Len(t, set, 3)
+	assert.Equal(t, "block", set[0].name)
+
+	domain := set.domainCollectors()
+	require.Len(t, domain, 2)
+	assert.Equal(t, "vcpu", domain[1].name)
+	host := set.hostCollectors()
+	require.Len(t, host, 1)
+	assert.Equal(t, "storagepool", host[0].name)
+
+	_, err = newCollectorSet([]string{"bogus"})
+	assert.Error(t, err)
+}
+
+func TestEnabledCollectors(t *testing.T) {
+	// every collector but storagevolume is enabled by default
+	assert.Equal(t, len(collectorNames())-1, len(enabledCollectors()))
+	assert.NotContains(t, enabledCollectors(), "storagevolume")
+
+	*disableDefaultCollectors = true
+	collectorFlagged["memory"] = true
+	defer func() {
+		*disableDefaultCollectors = false
+		delete(collectorFlagged, "memory")
+	}()
+	assert.Equal(t, []string{"memory"}, enabledCollectors())
+}
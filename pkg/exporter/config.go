@@ -0,0 +1,178 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the --config.file YAML file. Settings it leaves
+// out keep the value of the corresponding command-line flag.
+type Config struct {
+	// Libvirt configures the target exposed on /metrics.
+	Libvirt LibvirtConfig `yaml:"libvirt"`
+	// Collectors enables or disables collectors by name, overriding the
+	// --collector.<name> flags.
+	Collectors map[string]bool `yaml:"collectors"`
+	// Filters selects the domains that are collected.
+	Filters FilterConfig `yaml:"filters"`
+	// Labels chooses the labels identifying a domain. Lists it leaves out
+	// keep their default.
+	Labels *LabelConfig `yaml:"labels"`
+	// Modules holds the per-target connection settings used by the probe
+	// endpoint, selected with the module URL parameter.
+	Modules map[string]Module `yaml:"modules"`
+
+	// hash is the SHA-256 of the file content.
+	hash [sha256.Size]byte
+}
+
+// LibvirtConfig overrides the --libvirt.* flags of the /metrics target.
+type LibvirtConfig struct {
+	URI            string           `yaml:"uri"`
+	Driver         string           `yaml:"driver"`
+	TLS            *TLSConfig       `yaml:"tls"`
+	PollInterval   *model.Duration  `yaml:"poll_interval"`
+	SampleInterval *model.Duration  `yaml:"sample_interval"`
+	RateWindows    []model.Duration `yaml:"rate_windows"`
+}
+
+// Module describes how the probe endpoint authenticates against a target.
+type Module struct {
+	// Driver is opened when the target is a local socket path.
+	Driver string    `yaml:"driver"`
+	TLS    TLSConfig `yaml:"tls"`
+}
+
+// DefaultModule is used for probes without a module parameter unless the
+// configuration file defines a module named "default".
+var DefaultModule = Module{
+	Driver: string(libvirt.QEMUSystem),
+}
+
+// LoadConfig reads and validates a configuration file.
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{hash: sha256.Sum256(content)}
+	if err = yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	for name := range cfg.Collectors {
+		if _, ok := collectors[name]; !ok {
+			return nil, fmt.Errorf("error parsing config file %s: unknown collector %q", path, name)
+		}
+	}
+	if err = cfg.Filters.validate(); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	if cfg.Labels != nil {
+		if cfg.Labels.Domain == nil {
+			cfg.Labels.Domain = DefaultLabelConfig.Domain
+		}
+		if cfg.Labels.Info == nil {
+			cfg.Labels.Info = DefaultLabelConfig.Info
+		}
+		if err = cfg.Labels.validate(); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	}
+	for name, module := range cfg.Modules {
+		if module.Driver == "" {
+			module.Driver = DefaultModule.Driver
+			cfg.Modules[name] = module
+		}
+	}
+	return cfg, nil
+}
+
+// module returns the named module, falling back to DefaultModule for the
+// default name.
+func (c *Config) module(name string) (Module, bool) {
+	if name == "" {
+		name = "default"
+	}
+	if c != nil {
+		if module, ok := c.Modules[name]; ok {
+			return module, true
+		}
+	}
+	if name == "default" {
+		return DefaultModule, true
+	}
+	return Module{}, false
+}
+
+// Target returns the libvirt URI and driver of the /metrics target, falling
+// back to uri and driver where the file does not set them.
+func (c *Config) Target(uri string, driver libvirt.ConnectURI) (string, libvirt.ConnectURI) {
+	if c.Libvirt.URI != "" {
+		uri = c.Libvirt.URI
+	}
+	if c.Libvirt.Driver != "" {
+		driver = libvirt.ConnectURI(c.Libvirt.Driver)
+	}
+	return uri, driver
+}
+
+// Options returns the exporter options for the settings of the file. They
+// are meant to be applied after the options built from the flags.
+func (c *Config) Options() []Option {
+	var opts []Option
+	if c.Libvirt.TLS != nil {
+		opts = append(opts, WithTLSConfig(*c.Libvirt.TLS))
+	}
+	if c.Libvirt.PollInterval != nil {
+		opts = append(opts, WithPollInterval(time.Duration(*c.Libvirt.PollInterval)))
+	}
+	if c.Libvirt.SampleInterval != nil || c.Libvirt.RateWindows != nil {
+		opts = append(opts, func(e *LibvirtExporter) {
+			if c.Libvirt.SampleInterval != nil {
+				e.sampleInterval = time.Duration(*c.Libvirt.SampleInterval)
+			}
+			if c.Libvirt.RateWindows != nil {
+				e.rateWindows = make([]time.Duration, 0, len(c.Libvirt.RateWindows))
+				for _, window := range c.Libvirt.RateWindows {
+					e.rateWindows = append(e.rateWindows, time.Duration(window))
+				}
+			}
+		})
+	}
+	if len(c.Filters.Include) > 0 || len(c.Filters.Exclude) > 0 {
+		opts = append(opts, WithDomainFilter(c.Filters))
+	}
+	if c.Labels != nil {
+		opts = append(opts, WithLabels(*c.Labels))
+	}
+	if len(c.Collectors) > 0 {
+		opts = append(opts, WithCollectors(c.collectorNames(enabledCollectors())...))
+	}
+	return opts
+}
+
+// collectorNames returns the enabled collectors with the overrides of the
+// file applied.
+func (c *Config) collectorNames(enabled []string) []string {
+	state := make(map[string]bool, len(collectors))
+	for _, name := range enabled {
+		state[name] = true
+	}
+	for name, on := range c.Collectors {
+		state[name] = on
+	}
+
+	var names []string
+	for _, name := range collectorNames() {
+		if state[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
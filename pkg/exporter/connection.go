@@ -0,0 +1,225 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultKeepaliveInterval   = 5 * time.Second
+	defaultReconnectBackoffMin = 1 * time.Second
+	defaultReconnectBackoffMax = 1 * time.Minute
+)
+
+var (
+	libvirtConnectionUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "connection", "up"),
+		"Whether the exporter currently holds an open connection to libvirt.",
+		nil,
+		nil)
+	libvirtConnectionAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "connection", "age_seconds"),
+		"Seconds since the current libvirt connection was established.",
+		nil,
+		nil)
+	libvirtConnectionReconnectsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "connection", "reconnects_total"),
+		"Number of times the libvirt connection was re-established after it had been lost.",
+		nil,
+		nil)
+	libvirtConnectionLastErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "connection", "last_error_info"),
+		"Last error seen while connecting to or talking to libvirt.",
+		[]string{"error"},
+		nil)
+	libvirtConnectionLastErrorTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "connection", "last_error_timestamp_seconds"),
+		"Unix timestamp of the last libvirt connection error.",
+		nil,
+		nil)
+)
+
+// libvirtConnection owns a single long-lived connection to libvirt. A background
+// goroutine pings libvirt every keepalive interval to detect dead connections
+// and re-dials with exponential backoff once the connection is lost.
+type libvirtConnection struct {
+	target     libvirtTarget
+	keepalive  time.Duration
+	backoffMin time.Duration
+	backoffMax time.Duration
+	logger     log.Logger
+
+	mu            sync.Mutex
+	l             *libvirt.Libvirt
+	connectedAt   time.Time
+	everConnected bool
+	reconnects    uint64
+	lastErr       error
+	lastErrAt     time.Time
+	backoff       time.Duration
+	nextAttempt   time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newLibvirtConnection(target libvirtTarget, keepalive, backoffMin, backoffMax time.Duration, logger log.Logger) *libvirtConnection {
+	return &libvirtConnection{
+		target:     target,
+		keepalive:  keepalive,
+		backoffMin: backoffMin,
+		backoffMax: backoffMax,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+}
+
+// start launches the keepalive goroutine.
+func (c *libvirtConnection) start() {
+	c.wg.Add(1)
+	go c.run()
+}
+
+// close stops the keepalive goroutine and disconnects from libvirt. It is
+// safe to call more than once.
+func (c *libvirtConnection) close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	c.wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.l == nil || !c.l.IsConnected() {
+		return nil
+	}
+	err := c.l.Disconnect()
+	c.l = nil
+	return err
+}
+
+func (c *libvirtConnection) run() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.keepalive)
+	defer ticker.Stop()
+
+	for {
+		c.ping()
+		select {
+		case <-ticker.C:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// ping checks an established connection with a cheap RPC, or tries to
+// re-establish a lost one once the backoff has expired.
+func (c *libvirtConnection) ping() {
+	c.mu.Lock()
+	l := c.l
+	c.mu.Unlock()
+
+	if l == nil || !l.IsConnected() {
+		if _, err := c.get(); err != nil {
+			_ = level.Debug(c.logger).Log("msg", "libvirt is still unreachable", "err", err)
+		}
+		return
+	}
+
+	if _, err := l.ConnectGetLibVersion(); err != nil {
+		_ = level.Warn(c.logger).Log("warn", "libvirt keepalive failed, dropping connection", "msg", err)
+		c.mu.Lock()
+		c.recordError(err)
+		if c.l == l {
+			c.l = nil
+		}
+		c.mu.Unlock()
+		_ = l.Disconnect()
+	}
+}
+
+// get returns the open libvirt connection, dialing a new one if the previous
+// connection was lost and the reconnect backoff has expired.
+func (c *libvirtConnection) get() (*libvirt.Libvirt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.l != nil {
+		if c.l.IsConnected() {
+			return c.l, nil
+		}
+		_ = level.Warn(c.logger).Log("warn", "lost connection to libvirt")
+		c.l = nil
+	}
+
+	now := time.Now()
+	if now.Before(c.nextAttempt) {
+		return nil, fmt.Errorf("waiting %s before reconnecting to libvirt: %w", c.nextAttempt.Sub(now).Round(time.Millisecond), c.lastErr)
+	}
+
+	l := libvirt.NewWithDialer(c.target.dialer)
+	if err := l.ConnectToURI(c.target.driver); err != nil {
+		c.recordError(err)
+		c.backoff *= 2
+		if c.backoff < c.backoffMin {
+			c.backoff = c.backoffMin
+		}
+		if c.backoff > c.backoffMax {
+			c.backoff = c.backoffMax
+		}
+		c.nextAttempt = now.Add(c.backoff)
+		return nil, err
+	}
+
+	if c.everConnected {
+		c.reconnects++
+		_ = level.Info(c.logger).Log("msg", "reconnected to libvirt", "reconnects", c.reconnects)
+	}
+	c.everConnected = true
+	c.connectedAt = now
+	c.backoff = 0
+	c.nextAttempt = time.Time{}
+	c.l = l
+	return l, nil
+}
+
+// recordError remembers err as the last connection error. c.mu must be held.
+func (c *libvirtConnection) recordError(err error) {
+	c.lastErr = err
+	c.lastErrAt = time.Now()
+}
+
+func (c *libvirtConnection) describe(ch chan<- *prometheus.Desc) {
+	ch <- libvirtConnectionInfoDesc
+	ch <- libvirtConnectionUpDesc
+	ch <- libvirtConnectionAgeDesc
+	ch <- libvirtConnectionReconnectsDesc
+	ch <- libvirtConnectionLastErrorDesc
+	ch <- libvirtConnectionLastErrorTimeDesc
+}
+
+func (c *libvirtConnection) collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var up, age float64
+	if c.l != nil && c.l.IsConnected() {
+		up = 1
+		age = time.Since(c.connectedAt).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtConnectionInfoDesc, prometheus.GaugeValue, 1, c.target.transport, c.target.host, string(c.target.driver))
+	ch <- prometheus.MustNewConstMetric(libvirtConnectionUpDesc, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(libvirtConnectionAgeDesc, prometheus.GaugeValue, age)
+	ch <- prometheus.MustNewConstMetric(libvirtConnectionReconnectsDesc, prometheus.CounterValue, float64(c.reconnects))
+	if c.lastErr != nil {
+		ch <- prometheus.MustNewConstMetric(libvirtConnectionLastErrorDesc, prometheus.GaugeValue, 1, c.lastErr.Error())
+		ch <- prometheus.MustNewConstMetric(libvirtConnectionLastErrorTimeDesc, prometheus.GaugeValue, float64(c.lastErrAt.UnixNano())/1e9)
+	}
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingDialer struct {
+	dials int
+}
+
+func (d *failingDialer) Dial() (net.Conn, error) {
+	d.dials++
+	return nil, errors.New("connection refused")
+}
+
+func TestConnectionBackoff(t *testing.T) {
+	dialer := &failingDialer{}
+	target := libvirtTarget{transport: transportUnix, dialer: dialer, driver: "qemu:///system"}
+	c := newLibvirtConnection(target, time.Minute, time.Hour, 2*time.Hour, log.NewNopLogger())
+
+	_, err := c.get()
+	assert.Error(t, err)
+	assert.Equal(t, 1, dialer.dials)
+	assert.Equal(t, time.Hour, c.backoff)
+
+	// a second attempt within the backoff window must not dial again
+	_, err = c.get()
+	assert.ErrorContains(t, err, "before reconnecting")
+	assert.Equal(t, 1, dialer.dials)
+
+	// once the window has passed the backoff doubles, capped at the maximum
+	c.nextAttempt = time.Time{}
+	_, _ = c.get()
+	assert.Equal(t, 2*time.Hour, c.backoff)
+	c.nextAttempt = time.Time{}
+	_, _ = c.get()
+	assert.Equal(t, 2*time.Hour, c.backoff)
+	assert.Equal(t, 3, dialer.dials)
+}
+
+func TestConnectionCloseTwice(t *testing.T) {
+	target := libvirtTarget{transport: transportUnix, dialer: &failingDialer{}, driver: "qemu:///system"}
+	c := newLibvirtConnection(target, time.Minute, time.Hour, 2*time.Hour, log.NewNopLogger())
+	c.start()
+
+	assert.NoError(t, c.close())
+	assert.NotPanics(t, func() { assert.NoError(t, c.close()) })
+}
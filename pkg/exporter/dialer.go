@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/digitalocean/go-libvirt/socket"
+	"github.com/digitalocean/go-libvirt/socket/dialers"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	transportUnix = "unix"
+	transportTCP  = "tcp"
+	transportTLS  = "tls"
+
+	defaultDialTimeout = 5 * time.Second
+	defaultLocalSocket = "/var/run/libvirt/libvirt-sock"
+	defaultTCPPort     = "16509"
+	defaultTLSPort     = "16514"
+
+	// libvirt's default client PKI layout, see https://libvirt.org/kbase/tlscerts.html
+	defaultTLSCertFile = "/etc/pki/libvirt/clientcert.pem"
+	defaultTLSKeyFile  = "/etc/pki/libvirt/private/clientkey.pem"
+	defaultTLSCAFile   = "/etc/pki/CA/cacert.pem"
+)
+
+var libvirtConnectionInfoDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("libvirt_exporter", "connection", "info"),
+	"Transport, host and driver used to reach libvirt.",
+	[]string{"transport", "host", "driver"},
+	nil)
+
+// TLSConfig holds the client certificate settings for qemu+tls:// URIs.
+// Empty paths fall back to libvirt's default client PKI locations.
+type TLSConfig struct {
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	CAFile             string `yaml:"ca_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// libvirtTarget describes how to reach libvirt: the dialer for the transport
+// and the driver URI that is opened once the socket is established.
+type libvirtTarget struct {
+	transport string
+	host      string
+	dialer    socket.Dialer
+	driver    libvirt.ConnectURI
+}
+
+// parseLibvirtURI turns the --libvirt.uri value into a libvirtTarget.
+// A plain path is treated as a local unix socket and opened with the given
+// driver, anything else is parsed as a full libvirt URI such as
+// qemu+tcp://host/system or qemu+tls://host:16514/system.
+// Only transports without SASL are supported; the remote libvirtd must be
+// configured with auth_tcp = "none" or certificate based TLS auth.
+func parseLibvirtURI(uri string, driver libvirt.ConnectURI, tlsConfig TLSConfig, timeout time.Duration) (libvirtTarget, error) {
+	if !strings.Contains(uri, "://") {
+		return libvirtTarget{
+			transport: transportUnix,
+			dialer:    dialers.NewLocal(dialers.WithSocket(uri), dialers.WithLocalTimeout(timeout)),
+			driver:    driver,
+		}, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return libvirtTarget{}, fmt.Errorf("invalid libvirt URI %q: %w", uri, err)
+	}
+
+	transport := transportUnix
+	if scheme := strings.SplitN(u.Scheme, "+", 2); len(scheme) > 1 {
+		transport = scheme[1]
+	} else if u.Host != "" {
+		// libvirt defaults to TLS for remote URIs without an explicit transport
+		transport = transportTLS
+	}
+
+	target := libvirtTarget{
+		transport: transport,
+		host:      u.Hostname(),
+		driver:    libvirt.RemoteURI(u),
+	}
+	query := u.Query()
+
+	switch transport {
+	case transportUnix:
+		sock := query.Get("socket")
+		if sock == "" {
+			sock = defaultLocalSocket
+		}
+		target.dialer = dialers.NewLocal(dialers.WithSocket(sock), dialers.WithLocalTimeout(timeout))
+	case transportTCP:
+		if target.host == "" {
+			return libvirtTarget{}, fmt.Errorf("libvirt URI %q has no host", uri)
+		}
+		port := u.Port()
+		if port == "" {
+			port = defaultTCPPort
+		}
+		target.dialer = dialers.NewRemote(target.host, dialers.UsePort(port), dialers.WithRemoteTimeout(timeout))
+	case transportTLS:
+		if target.host == "" {
+			return libvirtTarget{}, fmt.Errorf("libvirt URI %q has no host", uri)
+		}
+		port := u.Port()
+		if port == "" {
+			port = defaultTLSPort
+		}
+		if pkiPath := query.Get("pkipath"); pkiPath != "" {
+			if tlsConfig.CertFile == "" {
+				tlsConfig.CertFile = filepath.Join(pkiPath, "clientcert.pem")
+			}
+			if tlsConfig.KeyFile == "" {
+				tlsConfig.KeyFile = filepath.Join(pkiPath, "clientkey.pem")
+			}
+			if tlsConfig.CAFile == "" {
+				tlsConfig.CAFile = filepath.Join(pkiPath, "cacert.pem")
+			}
+		}
+		if query.Get("no_verify") == "1" {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		target.dialer = newTLSDialer(net.JoinHostPort(target.host, port), target.host, tlsConfig, timeout)
+	default:
+		return libvirtTarget{}, fmt.Errorf("unsupported libvirt transport %q in %q", transport, uri)
+	}
+
+	return target, nil
+}
+
+// tlsDialer connects to a remote libvirtd over TLS using explicit client
+// certificate, key and CA files.
+type tlsDialer struct {
+	addr       string
+	serverName string
+	config     TLSConfig
+	timeout    time.Duration
+}
+
+func newTLSDialer(addr, serverName string, config TLSConfig, timeout time.Duration) *tlsDialer {
+	if config.CertFile == "" {
+		config.CertFile = defaultTLSCertFile
+	}
+	if config.KeyFile == "" {
+		config.KeyFile = defaultTLSKeyFile
+	}
+	if config.CAFile == "" {
+		config.CAFile = defaultTLSCAFile
+	}
+	return &tlsDialer{
+		addr:       addr,
+		serverName: serverName,
+		config:     config,
+		timeout:    timeout,
+	}
+}
+
+func (d *tlsDialer) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(d.config.CertFile, d.config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load tls client certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		ServerName:         d.serverName,
+		InsecureSkipVerify: d.config.InsecureSkipVerify, //nolint:gosec
+	}
+
+	ca, err := os.ReadFile(d.config.CAFile)
+	if err != nil {
+		if d.config.InsecureSkipVerify && errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("could not read tls CA certificate: %w", err)
+	}
+	cfg.RootCAs = x509.NewCertPool()
+	if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", d.config.CAFile)
+	}
+	return cfg, nil
+}
+
+// Dial implements socket.Dialer.
+func (d *tlsDialer) Dial() (net.Conn, error) {
+	cfg, err := d.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := tls.DialWithDialer(&net.Dialer{Timeout: d.timeout}, "tcp", d.addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// After the handshake libvirtd writes a single byte telling whether it
+	// accepted our client certificate.
+	_ = c.SetReadDeadline(time.Now().Add(d.timeout))
+	buf := make([]byte, 1)
+	if _, err := c.Read(buf); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if buf[0] != 1 {
+		c.Close()
+		return nil, errors.New("libvirtd rejected the client certificate")
+	}
+	_ = c.SetReadDeadline(time.Time{})
+
+	return c, nil
+}
@@ -0,0 +1,150 @@
+package exporter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLibvirtURI(t *testing.T) {
+	for _, tc := range []struct {
+		uri       string
+		transport string
+		host      string
+		driver    libvirt.ConnectURI
+	}{
+		{"/var/run/libvirt/libvirt-sock-ro", transportUnix, "", libvirt.QEMUSystem},
+		{"qemu:///system", transportUnix, "", "qemu:///system"},
+		{"qemu+unix:///session?socket=/tmp/sock", transportUnix, "", "qemu:///session"},
+		{"qemu+tcp://hv1.example.com/system", transportTCP, "hv1.example.com", "qemu:///system"},
+		{"qemu+tls://hv2.example.com:16515/system", transportTLS, "hv2.example.com", "qemu:///system"},
+		{"qemu://hv3.example.com/system", transportTLS, "hv3.example.com", "qemu:///system"},
+	} {
+		target, err := parseLibvirtURI(tc.uri, libvirt.QEMUSystem, TLSConfig{}, time.Second)
+		require.NoError(t, err, tc.uri)
+		assert.Equal(t, tc.transport, target.transport, tc.uri)
+		assert.Equal(t, tc.host, target.host, tc.uri)
+		assert.Equal(t, tc.driver, target.driver, tc.uri)
+	}
+
+	for _, uri := range []string{"qemu+ssh://hv1/system", "qemu+tcp:///system"} {
+		_, err := parseLibvirtURI(uri, libvirt.QEMUSystem, TLSConfig{}, time.Second)
+		assert.Error(t, err, uri)
+	}
+}
+
+func TestTLSDialer(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newTestCert(t, nil, nil, true)
+	server, serverKey := newTestCert(t, ca, caKey, false)
+	client, clientKey := newTestCert(t, ca, caKey, false)
+	writeTestPEM(t, filepath.Join(dir, "cacert.pem"), "CERTIFICATE", ca.Raw)
+	writeTestPEM(t, filepath.Join(dir, "clientcert.pem"), "CERTIFICATE", client.Raw)
+	writeTestPEM(t, filepath.Join(dir, "clientkey.pem"), "EC PRIVATE KEY", marshalTestKey(t, clientKey))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.Raw}, PrivateKey: serverKey}},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// mimic libvirtd, which confirms an accepted client certificate with a single byte
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if err := c.(*tls.Conn).Handshake(); err == nil {
+				_, _ = c.Write([]byte{1})
+			}
+			c.Close()
+		}
+	}()
+
+	target, err := parseLibvirtURI("qemu+tls://"+ln.Addr().String()+"/system", libvirt.QEMUSystem, TLSConfig{
+		CertFile: filepath.Join(dir, "clientcert.pem"),
+		KeyFile:  filepath.Join(dir, "clientkey.pem"),
+		CAFile:   filepath.Join(dir, "cacert.pem"),
+	}, time.Second)
+	require.NoError(t, err)
+
+	conn, err := target.dialer.Dial()
+	require.NoError(t, err)
+	conn.Close()
+
+	// pkipath picks up libvirt's file names from a single directory
+	target, err = parseLibvirtURI("qemu+tls://"+ln.Addr().String()+"/system?pkipath="+dir, libvirt.QEMUSystem, TLSConfig{}, time.Second)
+	require.NoError(t, err)
+	conn, err = target.dialer.Dial()
+	require.NoError(t, err)
+	conn.Close()
+
+	// a client certificate not signed by the CA is rejected
+	other, otherKey := newTestCert(t, nil, nil, false)
+	writeTestPEM(t, filepath.Join(dir, "othercert.pem"), "CERTIFICATE", other.Raw)
+	writeTestPEM(t, filepath.Join(dir, "otherkey.pem"), "EC PRIVATE KEY", marshalTestKey(t, otherKey))
+	target, err = parseLibvirtURI("qemu+tls://"+ln.Addr().String()+"/system", libvirt.QEMUSystem, TLSConfig{
+		CertFile: filepath.Join(dir, "othercert.pem"),
+		KeyFile:  filepath.Join(dir, "otherkey.pem"),
+		CAFile:   filepath.Join(dir, "cacert.pem"),
+	}, time.Second)
+	require.NoError(t, err)
+	_, err = target.dialer.Dial()
+	assert.Error(t, err)
+}
+
+func newTestCert(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "libvirt-exporter test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func marshalTestKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return der
+}
+
+func writeTestPEM(t *testing.T, path, blockType string, der []byte) {
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
@@ -0,0 +1,122 @@
+package exporter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtDomainCacheHitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "domain_cache", "hits_total"),
+		"Number of domains whose parsed XML was served from the cache.",
+		nil,
+		nil)
+	libvirtDomainCacheMissesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "domain_cache", "misses_total"),
+		"Number of domains whose XML had to be fetched and parsed.",
+		nil,
+		nil)
+	libvirtDomainCacheEntriesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "domain_cache", "entries"),
+		"Number of domains currently in the cache.",
+		nil,
+		nil)
+)
+
+type cachedDomain struct {
+	meta    domainMeta
+	expires time.Time
+}
+
+// domainCache keeps the domainMeta parsed from each domain's XML, keyed by
+// UUID. Entries expire after ttl and are invalidated by domain events.
+type domainCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[libvirt.UUID]cachedDomain
+
+	hits, misses atomic.Uint64
+}
+
+func newDomainCache(ttl time.Duration) *domainCache {
+	return &domainCache{
+		ttl:     ttl,
+		entries: make(map[libvirt.UUID]cachedDomain),
+	}
+}
+
+// get returns the cached metadata of domain. The libvirt.Domain itself is
+// taken from the caller, since its ID changes whenever the domain restarts.
+func (c *domainCache) get(domain libvirt.Domain, now time.Time) (domainMeta, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[domain.UUID]
+	if ok && !now.Before(entry.expires) {
+		delete(c.entries, domain.UUID)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return domainMeta{}, false
+	}
+	c.hits.Add(1)
+	entry.meta.libvirtDomain = domain
+	return entry.meta, true
+}
+
+func (c *domainCache) put(meta domainMeta, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[meta.libvirtDomain.UUID] = cachedDomain{meta: meta, expires: now.Add(c.ttl)}
+}
+
+func (c *domainCache) invalidate(uuid libvirt.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, uuid)
+}
+
+// reset drops all entries, e.g. after events may have been missed.
+func (c *domainCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[libvirt.UUID]cachedDomain)
+}
+
+// retain drops the entries of domains that are no longer listed.
+func (c *domainCache) retain(domains []libvirt.Domain) {
+	listed := make(map[libvirt.UUID]bool, len(domains))
+	for _, domain := range domains {
+		listed[domain.UUID] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uuid := range c.entries {
+		if !listed[uuid] {
+			delete(c.entries, uuid)
+		}
+	}
+}
+
+func (c *domainCache) describe(ch chan<- *prometheus.Desc) {
+	ch <- libvirtDomainCacheHitsDesc
+	ch <- libvirtDomainCacheMissesDesc
+	ch <- libvirtDomainCacheEntriesDesc
+}
+
+func (c *domainCache) collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(libvirtDomainCacheHitsDesc, prometheus.CounterValue, float64(c.hits.Load()))
+	ch <- prometheus.MustNewConstMetric(libvirtDomainCacheMissesDesc, prometheus.CounterValue, float64(c.misses.Load()))
+	ch <- prometheus.MustNewConstMetric(libvirtDomainCacheEntriesDesc, prometheus.GaugeValue, float64(entries))
+}
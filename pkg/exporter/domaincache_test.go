@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainCache(t *testing.T) {
+	now := time.Now()
+	cache := newDomainCache(time.Minute)
+	vm1 := libvirt.Domain{Name: "vm1", UUID: libvirt.UUID{1}, ID: 1}
+	vm2 := libvirt.Domain{Name: "vm2", UUID: libvirt.UUID{2}, ID: 2}
+
+	_, ok := cache.get(vm1, now)
+	assert.False(t, ok)
+	cache.put(domainMeta{libvirtDomain: vm1, instanceName: "instance-1"}, now)
+	cache.put(domainMeta{libvirtDomain: vm2}, now)
+
+	// the domain ID changes across restarts and is taken from the listing
+	restarted := vm1
+	restarted.ID = 7
+	meta, ok := cache.get(restarted, now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, "instance-1", meta.instanceName)
+	assert.Equal(t, int32(7), meta.libvirtDomain.ID)
+
+	_, ok = cache.get(vm1, now.Add(time.Minute))
+	assert.False(t, ok, "entry should have expired")
+
+	cache.invalidate(vm2.UUID)
+	_, ok = cache.get(vm2, now)
+	assert.False(t, ok)
+
+	cache.put(domainMeta{libvirtDomain: vm1}, now)
+	cache.put(domainMeta{libvirtDomain: vm2}, now)
+	cache.retain([]libvirt.Domain{vm2})
+	assert.Len(t, cache.entries, 1)
+	cache.reset()
+	assert.Empty(t, cache.entries)
+
+	assert.Equal(t, uint64(1), cache.hits.Load())
+	assert.Equal(t, uint64(3), cache.misses.Load())
+}
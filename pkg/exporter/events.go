@@ -0,0 +1,308 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// undefinedEventRetention is how long the event counters of an undefined
+// domain are kept before they are dropped.
+const undefinedEventRetention = time.Hour
+
+var (
+	libvirtDomainLifecycleEventsDesc = newDomainDesc(
+		prometheus.BuildFQName("libvirt_domain", "", "lifecycle_events_total"),
+		"Number of lifecycle events libvirt reported for the domain since the exporter subscribed.",
+		[]string{"event", "reason"},
+		nil)
+	libvirtEventsSubscribedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "", "events_subscribed"),
+		"Whether the exporter is currently subscribed to libvirt domain lifecycle events.",
+		nil,
+		nil)
+)
+
+// lifecycleEventNames maps libvirt's virDomainEventType to the event label.
+var lifecycleEventNames = map[libvirt.DomainEventType]string{
+	libvirt.DomainEventDefined:     "defined",
+	libvirt.DomainEventUndefined:   "undefined",
+	libvirt.DomainEventStarted:     "started",
+	libvirt.DomainEventSuspended:   "suspended",
+	libvirt.DomainEventResumed:     "resumed",
+	libvirt.DomainEventStopped:     "stopped",
+	libvirt.DomainEventShutdown:    "shutdown",
+	libvirt.DomainEventPmsuspended: "pmsuspended",
+	libvirt.DomainEventCrashed:     "crashed",
+}
+
+// lifecycleReasonNames maps the detail of each event type to the reason label.
+var lifecycleReasonNames = map[libvirt.DomainEventType][]string{
+	libvirt.DomainEventDefined:     {"added", "updated", "renamed", "from_snapshot"},
+	libvirt.DomainEventUndefined:   {"removed", "renamed"},
+	libvirt.DomainEventStarted:     {"booted", "migrated", "restored", "from_snapshot", "wakeup"},
+	libvirt.DomainEventSuspended:   {"paused", "migrated", "ioerror", "watchdog", "restored", "from_snapshot", "api_error", "postcopy", "postcopy_failed"},
+	libvirt.DomainEventResumed:     {"unpaused", "migrated", "from_snapshot", "postcopy"},
+	libvirt.DomainEventStopped:     {"shutdown", "destroyed", "crashed", "migrated", "saved", "failed", "from_snapshot"},
+	libvirt.DomainEventShutdown:    {"finished", "guest", "host"},
+	libvirt.DomainEventPmsuspended: {"memory", "disk"},
+	libvirt.DomainEventCrashed:     {"panicked", "crashloaded"},
+}
+
+// lifecycleEventLabels returns the event and reason labels of a lifecycle
+// event, falling back to the numeric values for types libvirt added later.
+func lifecycleEventLabels(event, detail int32) (string, string) {
+	t := libvirt.DomainEventType(event)
+	name, ok := lifecycleEventNames[t]
+	if !ok {
+		name = strconv.Itoa(int(event))
+	}
+	reasons := lifecycleReasonNames[t]
+	if detail >= 0 && int(detail) < len(reasons) {
+		return name, reasons[detail]
+	}
+	return name, strconv.Itoa(int(detail))
+}
+
+// formatUUID returns the textual form of a libvirt UUID, as used in the
+// domain XML.
+func formatUUID(u libvirt.UUID) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// lifecycleKey identifies a lifecycle counter. labels are the domain label
+// values joined by labelSeparator.
+type lifecycleKey struct {
+	labels, uuid, event, reason string
+}
+
+const labelSeparator = "\xff"
+
+// lifecycleEvents subscribes to the lifecycle events of all domains and
+// counts them, so state changes between two scrapes are not lost. The
+// subscription is renewed whenever the libvirt connection is re-established.
+// If a domain cache is given, domain events also invalidate its entries.
+// Without count, the events are only used for that and not counted.
+// Domains the name and UUID rules of the filter leave out are not counted, and
+// the counters carry the domain labels of the label schema.
+type lifecycleEvents struct {
+	conn   *libvirtConnection
+	retry  time.Duration
+	count  bool
+	cache  *domainCache
+	filter *FilterConfig
+	labels *labelSchema
+	logger log.Logger
+
+	mu         sync.Mutex
+	counts     map[lifecycleKey]uint64
+	undefined  map[string]time.Time
+	subscribed bool
+	// domains are the domains of the last collection, for the label values
+	// an event does not carry
+	domains map[libvirt.UUID]domainMeta
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newLifecycleEvents(conn *libvirtConnection, retry time.Duration, count bool, cache *domainCache, filter *FilterConfig, labels *labelSchema, logger log.Logger) *lifecycleEvents {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycleEvents{
+		conn:      conn,
+		retry:     retry,
+		count:     count,
+		cache:     cache,
+		filter:    filter,
+		labels:    labels,
+		logger:    logger,
+		counts:    make(map[lifecycleKey]uint64),
+		undefined: make(map[string]time.Time),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (e *lifecycleEvents) start() {
+	e.wg.Add(1)
+	go e.run()
+}
+
+func (e *lifecycleEvents) stop() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+func (e *lifecycleEvents) run() {
+	defer e.wg.Done()
+
+	for {
+		if err := e.subscribe(); err != nil {
+			_ = level.Warn(e.logger).Log("warn", "failed to subscribe to lifecycle events", "msg", err)
+		}
+		select {
+		case <-time.After(e.retry):
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// subscribe receives events until the connection is lost or the exporter is
+// closed.
+func (e *lifecycleEvents) subscribe() error {
+	l, err := e.conn.get()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+
+	events, err := l.LifecycleEvents(ctx)
+	if err != nil {
+		return err
+	}
+	if e.cache != nil {
+		if err = e.subscribeCacheEvents(ctx, l); err != nil {
+			return err
+		}
+		// events were not seen while unsubscribed
+		e.cache.reset()
+	}
+
+	e.setSubscribed(true)
+	defer e.setSubscribed(false)
+	_ = level.Debug(e.logger).Log("msg", "subscribed to lifecycle events")
+
+	for ev := range events {
+		e.record(ev)
+	}
+	return nil
+}
+
+// cacheEvents are the events that change a domain's XML without a lifecycle
+// event.
+var cacheEvents = []libvirt.DomainEventID{
+	libvirt.DomainEventIDDeviceAdded,
+	libvirt.DomainEventIDDeviceRemoved,
+	libvirt.DomainEventIDMetadataChange,
+}
+
+// subscribeCacheEvents invalidates cache entries on cacheEvents until ctx is
+// cancelled or the connection is lost.
+func (e *lifecycleEvents) subscribeCacheEvents(ctx context.Context, l *libvirt.Libvirt) error {
+	for _, id := range cacheEvents {
+		events, err := l.SubscribeEvents(ctx, id, libvirt.OptDomain{})
+		if err != nil {
+			return err
+		}
+		go func() {
+			for ev := range events {
+				switch msg := ev.(type) {
+				case *libvirt.DomainEventCallbackDeviceAddedMsg:
+					e.cache.invalidate(msg.Dom.UUID)
+				case *libvirt.DomainEventCallbackDeviceRemovedMsg:
+					e.cache.invalidate(msg.Msg.Dom.UUID)
+				case *libvirt.DomainEventCallbackMetadataChangeMsg:
+					e.cache.invalidate(msg.Dom.UUID)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func (e *lifecycleEvents) setSubscribed(subscribed bool) {
+	e.mu.Lock()
+	e.subscribed = subscribed
+	e.mu.Unlock()
+}
+
+func (e *lifecycleEvents) record(ev libvirt.DomainEventLifecycleMsg) {
+	event, reason := lifecycleEventLabels(ev.Event, ev.Detail)
+	uuid := formatUUID(ev.Dom.UUID)
+	// besides definitions, starting and stopping change the live XML too,
+	// e.g. the target devices of interfaces
+	if e.cache != nil {
+		e.cache.invalidate(ev.Dom.UUID)
+	}
+	// the counts are only expired when they are collected
+	if !e.count || e.filter.leavesOut(ev.Dom.Name, uuid) {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// of domains not collected yet only the name and UUID are known
+	meta := e.domains[ev.Dom.UUID]
+	meta.domainName, meta.libvirtDomain = ev.Dom.Name, ev.Dom
+	labels := strings.Join(e.labels.values(meta, e.labels.domain), labelSeparator)
+	e.counts[lifecycleKey{labels: labels, uuid: uuid, event: event, reason: reason}]++
+	switch libvirt.DomainEventType(ev.Event) {
+	case libvirt.DomainEventUndefined:
+		e.undefined[uuid] = time.Now()
+	case libvirt.DomainEventDefined:
+		delete(e.undefined, uuid)
+	}
+}
+
+// observe remembers the domains of a collection, whose labels events of
+// them get.
+func (e *lifecycleEvents) observe(domains []domainMeta) {
+	byUUID := make(map[libvirt.UUID]domainMeta, len(domains))
+	for _, domain := range domains {
+		byUUID[domain.libvirtDomain.UUID] = domain
+	}
+	e.mu.Lock()
+	e.domains = byUUID
+	e.mu.Unlock()
+}
+
+// expire drops the counters of domains undefined longer than the retention.
+func (e *lifecycleEvents) expire(now time.Time) {
+	for uuid, at := range e.undefined {
+		if now.Sub(at) < undefinedEventRetention {
+			continue
+		}
+		for key := range e.counts {
+			if key.uuid == uuid {
+				delete(e.counts, key)
+			}
+		}
+		delete(e.undefined, uuid)
+	}
+}
+
+func (e *lifecycleEvents) describe(ch chan<- *prometheus.Desc) {
+	e.labels.describe(ch, libvirtDomainLifecycleEventsDesc)
+	ch <- libvirtEventsSubscribedDesc
+}
+
+func (e *lifecycleEvents) collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.expire(time.Now())
+	for key, count := range e.counts {
+		ch <- prometheus.MustNewConstMetric(
+			e.labels.desc(libvirtDomainLifecycleEventsDesc),
+			prometheus.CounterValue,
+			float64(count),
+			append(strings.Split(key.labels, labelSeparator), key.event, key.reason)...)
+	}
+
+	subscribed := 0.0
+	if e.subscribed {
+		subscribed = 1
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtEventsSubscribedDesc, prometheus.GaugeValue, subscribed)
+}
@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleEventLabels(t *testing.T) {
+	event, reason := lifecycleEventLabels(int32(libvirt.DomainEventStopped), int32(libvirt.DomainEventStoppedCrashed))
+	assert.Equal(t, "stopped", event)
+	assert.Equal(t, "crashed", reason)
+
+	event, reason = lifecycleEventLabels(int32(libvirt.DomainEventStarted), int32(libvirt.DomainEventStartedMigrated))
+	assert.Equal(t, "started", event)
+	assert.Equal(t, "migrated", reason)
+
+	event, reason = lifecycleEventLabels(42, 7)
+	assert.Equal(t, "42", event)
+	assert.Equal(t, "7", reason)
+}
+
+func TestFormatUUID(t *testing.T) {
+	u := libvirt.UUID{0x4d, 0xea, 0x22, 0xb3, 0x1d, 0x52, 0xd8, 0xf3, 0x26, 0x16, 0x78, 0x2f, 0xd3, 0x2c, 0x87, 0x0e}
+	assert.Equal(t, "4dea22b3-1d52-d8f3-2616-782fd32c870e", formatUUID(u))
+}
+
+func TestLifecycleEventsRecord(t *testing.T) {
+	labels, err := newLabelSchema(LabelConfig{Domain: []string{"domain", "project_id"}})
+	require.NoError(t, err)
+	filter := &FilterConfig{Exclude: []DomainRule{
+		{Name: &Regexp{regexp.MustCompile("^(?:infra-.*)$")}},
+		// cannot be told from an event
+		{Name: &Regexp{regexp.MustCompile("^(?:vm.*)$")}, States: []string{"shutoff"}},
+	}}
+	e := newLifecycleEvents(nil, time.Second, true, nil, filter, labels, log.NewNopLogger())
+	dom := libvirt.Domain{Name: "vm1", UUID: libvirt.UUID{1}}
+	e.observe([]domainMeta{{domainName: "vm1", projectId: "p1", libvirtDomain: dom}})
+
+	for i := 0; i < 3; i++ {
+		e.record(libvirt.DomainEventLifecycleMsg{Dom: dom, Event: int32(libvirt.DomainEventCrashed)})
+	}
+	e.record(libvirt.DomainEventLifecycleMsg{Dom: dom, Event: int32(libvirt.DomainEventUndefined)})
+	e.record(libvirt.DomainEventLifecycleMsg{Dom: libvirt.Domain{Name: "infra-1", UUID: libvirt.UUID{2}}, Event: int32(libvirt.DomainEventStarted)})
+
+	crashed := lifecycleKey{labels: "vm1" + labelSeparator + "p1", uuid: formatUUID(dom.UUID), event: "crashed", reason: "panicked"}
+	assert.Equal(t, uint64(3), e.counts[crashed])
+
+	metrics, err := collectToSlice(func(ch chan<- prometheus.Metric) error {
+		e.collect(ch)
+		return nil
+	})
+	require.NoError(t, err)
+	// crashed, undefined and the subscription gauge, not the excluded domain
+	require.Len(t, metrics, 3)
+	var pb dto.Metric
+	require.NoError(t, metrics[0].Write(&pb))
+	names := make([]string, 0, len(pb.GetLabel()))
+	for _, lp := range pb.GetLabel() {
+		names = append(names, lp.GetName())
+	}
+	assert.ElementsMatch(t, []string{"domain", "project_id", "event", "reason"}, names)
+
+	e.expire(time.Now().Add(undefinedEventRetention))
+	assert.Empty(t, e.counts)
+	assert.Empty(t, e.undefined)
+}
+
+func TestLifecycleEventsCacheOnly(t *testing.T) {
+	cache := newDomainCache(time.Minute)
+	e := newLifecycleEvents(nil, time.Second, false, cache, nil, nil, log.NewNopLogger())
+	dom := libvirt.Domain{Name: "vm1", UUID: libvirt.UUID{1}}
+	cache.put(domainMeta{libvirtDomain: dom}, time.Now())
+
+	e.record(libvirt.DomainEventLifecycleMsg{Dom: dom, Event: int32(libvirt.DomainEventUndefined)})
+	_, ok := cache.get(dom, time.Now())
+	assert.False(t, ok)
+	// nothing collects the counts, so they must not pile up
+	assert.Empty(t, e.counts)
+	assert.Empty(t, e.undefined)
+}
@@ -0,0 +1,236 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var libvirtDomainsFilteredDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("libvirt_exporter", "", "domains_filtered"),
+	"Number of domains the domain filters left out of the last scrape, by whether no include rule matched them (not_included) or an exclude rule did (excluded).",
+	[]string{"reason"},
+	nil)
+
+// domainStates maps the state names used in filter rules to libvirt states.
+var domainStates = map[string]libvirt.DomainState{
+	"nostate":     libvirt.DomainNostate,
+	"running":     libvirt.DomainRunning,
+	"blocked":     libvirt.DomainBlocked,
+	"paused":      libvirt.DomainPaused,
+	"shutdown":    libvirt.DomainShutdown,
+	"shutoff":     libvirt.DomainShutoff,
+	"crashed":     libvirt.DomainCrashed,
+	"pmsuspended": libvirt.DomainPmsuspended,
+}
+
+// Regexp is a regular expression of the configuration file. Like in
+// Prometheus relabelling, it has to match the whole value.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (re *Regexp) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	compiled, err := regexp.Compile("^(?:" + s + ")$")
+	if err != nil {
+		return err
+	}
+	re.Regexp = compiled
+	return nil
+}
+
+// DomainRule matches the domains that match every field it sets. Fields
+// holding a list match any of its values.
+type DomainRule struct {
+	Name       *Regexp  `yaml:"name"`
+	UUIDs      []string `yaml:"uuids"`
+	States     []string `yaml:"states"`
+	ProjectIDs []string `yaml:"project_ids"`
+	Flavors    []string `yaml:"flavors"`
+}
+
+func (r DomainRule) validate() error {
+	for _, state := range r.States {
+		if _, ok := domainStates[state]; !ok {
+			return fmt.Errorf("unknown domain state %q", state)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the domain matches the rule. state is only called
+// for rules on the state, since it costs a call to libvirt.
+func (r DomainRule) matches(domain domainMeta, state func() (libvirt.DomainState, error)) (bool, error) {
+	if !r.matchesIdentity(domain.domainName, formatUUID(domain.libvirtDomain.UUID)) {
+		return false, nil
+	}
+	if len(r.ProjectIDs) > 0 && !containsFold(r.ProjectIDs, domain.projectId) {
+		return false, nil
+	}
+	if len(r.Flavors) > 0 && !containsFold(r.Flavors, domain.flavorName) {
+		return false, nil
+	}
+	if len(r.States) > 0 {
+		current, err := state()
+		if err != nil {
+			return false, err
+		}
+		for _, name := range r.States {
+			if domainStates[name] == current {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// matchesIdentity reports whether the name and UUID of a domain match the
+// rule, regardless of its other fields.
+func (r DomainRule) matchesIdentity(name, uuid string) bool {
+	if r.Name != nil && !r.Name.MatchString(name) {
+		return false
+	}
+	return len(r.UUIDs) == 0 || containsFold(r.UUIDs, uuid)
+}
+
+// identityOnly reports whether the rule only has fields on the name and UUID.
+func (r DomainRule) identityOnly() bool {
+	return len(r.States) == 0 && len(r.ProjectIDs) == 0 && len(r.Flavors) == 0
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterConfig selects the domains that are collected: those matching any
+// include rule, or all domains without include rules, unless they match an
+// exclude rule.
+type FilterConfig struct {
+	Include []DomainRule `yaml:"include"`
+	Exclude []DomainRule `yaml:"exclude"`
+}
+
+func (c *FilterConfig) validate() error {
+	for _, rules := range [][]DomainRule{c.Include, c.Exclude} {
+		for _, rule := range rules {
+			if err := rule.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filterCounts is the number of domains a filter left out.
+type filterCounts struct {
+	notIncluded int
+	excluded    int
+}
+
+// apply returns the domains selected by the filter. A nil filter selects all
+// domains. Domains whose state cannot be read are left out as well.
+func (c *FilterConfig) apply(l *libvirt.Libvirt, domains []domainMeta, logger log.Logger) ([]domainMeta, filterCounts) {
+	var counts filterCounts
+	if c == nil || (len(c.Include) == 0 && len(c.Exclude) == 0) {
+		return domains, counts
+	}
+
+	selected := domains[:0]
+	for _, domain := range domains {
+		var (
+			state   libvirt.DomainState
+			fetched bool
+		)
+		stateOf := func() (libvirt.DomainState, error) {
+			if !fetched {
+				s, _, err := l.DomainGetState(domain.libvirtDomain, 0)
+				if err != nil {
+					return 0, err
+				}
+				state, fetched = libvirt.DomainState(s), true
+			}
+			return state, nil
+		}
+
+		included, excluded, err := c.match(domain, stateOf)
+		if err != nil {
+			if !libvirt.IsNotFound(err) {
+				_ = level.Warn(logger).Log("warn", "failed to get DomainGetState", "domain", domain.libvirtDomain.Name, "msg", err)
+			}
+			continue
+		}
+		switch {
+		case !included:
+			counts.notIncluded++
+		case excluded:
+			counts.excluded++
+		default:
+			selected = append(selected, domain)
+		}
+	}
+	return selected, counts
+}
+
+func (c *FilterConfig) match(domain domainMeta, state func() (libvirt.DomainState, error)) (included, excluded bool, err error) {
+	included = len(c.Include) == 0
+	for _, rule := range c.Include {
+		if included, err = rule.matches(domain, state); err != nil || included {
+			break
+		}
+	}
+	if err != nil || !included {
+		return included, false, err
+	}
+	for _, rule := range c.Exclude {
+		if excluded, err = rule.matches(domain, state); err != nil || excluded {
+			break
+		}
+	}
+	return included, excluded, err
+}
+
+// leavesOut reports whether the filter leaves out a domain of which only the
+// name and UUID are known, as of a domain event. Rules on other fields are
+// given the benefit of the doubt: include rules match on the name and UUID
+// alone, and exclude rules only apply without other fields.
+func (c *FilterConfig) leavesOut(name, uuid string) bool {
+	if c == nil {
+		return false
+	}
+	included := len(c.Include) == 0
+	for _, rule := range c.Include {
+		if rule.matchesIdentity(name, uuid) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return true
+	}
+	for _, rule := range c.Exclude {
+		if rule.identityOnly() && rule.matchesIdentity(name, uuid) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c filterCounts) collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(libvirtDomainsFilteredDesc, prometheus.GaugeValue, float64(c.notIncluded), "not_included")
+	ch <- prometheus.MustNewConstMetric(libvirtDomainsFilteredDesc, prometheus.GaugeValue, float64(c.excluded), "excluded")
+}
@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestFilterConfig(t *testing.T) {
+	var filter FilterConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+include:
+  - project_ids: [6F2A]
+  - name: infra-.*
+    states: [running]
+exclude:
+  - flavors: [m1.tiny]
+  - uuids: [01000000-0000-0000-0000-000000000000]
+`), &filter))
+	require.NoError(t, filter.validate())
+
+	domain := func(name string, uuid byte, project, flavor string) domainMeta {
+		return domainMeta{
+			domainName:    name,
+			projectId:     project,
+			flavorName:    flavor,
+			libvirtDomain: libvirt.Domain{Name: name, UUID: libvirt.UUID{uuid}},
+		}
+	}
+	running := func() (libvirt.DomainState, error) { return libvirt.DomainRunning, nil }
+	shutoff := func() (libvirt.DomainState, error) { return libvirt.DomainShutoff, nil }
+
+	for _, tc := range []struct {
+		domain             domainMeta
+		state              func() (libvirt.DomainState, error)
+		included, excluded bool
+	}{
+		{domain("tenant", 2, "6f2a", "m1.large"), shutoff, true, false},
+		{domain("tenant", 2, "6f2a", "m1.tiny"), shutoff, true, true},
+		{domain("tenant", 1, "6f2a", "m1.large"), shutoff, true, true},
+		{domain("tenant", 2, "7b3c", "m1.large"), running, false, false},
+		{domain("infra-dns", 2, "", ""), running, true, false},
+		{domain("infra-dns", 2, "", ""), shutoff, false, false},
+		// the name has to match as a whole
+		{domain("my-infra-dns", 2, "", ""), running, false, false},
+	} {
+		included, excluded, err := filter.match(tc.domain, tc.state)
+		require.NoError(t, err)
+		assert.Equal(t, tc.included, included, tc.domain.domainName)
+		assert.Equal(t, tc.excluded, excluded, tc.domain.domainName)
+	}
+
+	errState := errors.New("state")
+	_, _, err := filter.match(domain("infra-dns", 2, "", ""), func() (libvirt.DomainState, error) { return 0, errState })
+	assert.ErrorIs(t, err, errState)
+
+	bad := FilterConfig{Exclude: []DomainRule{{States: []string{"sleeping"}}}}
+	assert.Error(t, bad.validate())
+}
+
+func TestFilterApply(t *testing.T) {
+	domains := []domainMeta{
+		{domainName: "a", projectId: "p1"},
+		{domainName: "b", projectId: "p2"},
+		{domainName: "c", projectId: "p1", flavorName: "infra"},
+	}
+
+	var none *FilterConfig
+	selected, counts := none.apply(nil, domains, log.NewNopLogger())
+	assert.Len(t, selected, 3)
+	assert.Zero(t, counts)
+
+	filter := &FilterConfig{
+		Include: []DomainRule{{ProjectIDs: []string{"p1"}}},
+		Exclude: []DomainRule{{Flavors: []string{"infra"}}},
+	}
+	selected, counts = filter.apply(nil, domains, log.NewNopLogger())
+	require.Len(t, selected, 1)
+	assert.Equal(t, "a", selected[0].domainName)
+	assert.Equal(t, filterCounts{notIncluded: 1, excluded: 1}, counts)
+}
+
+func TestFilterLeavesOut(t *testing.T) {
+	var filter *FilterConfig
+	assert.False(t, filter.leavesOut("vm1", "01000000-0000-0000-0000-000000000000"))
+
+	filter = &FilterConfig{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+include:
+  - name: web-.*
+    states: [running]
+  - project_ids: [6F2A]
+exclude:
+  - uuids: [01000000-0000-0000-0000-000000000000]
+  - flavors: [m1.tiny]
+`), filter))
+	// the project of an event is unknown, so the project rule includes it
+	assert.False(t, filter.leavesOut("db-1", "02000000-0000-0000-0000-000000000000"))
+	assert.True(t, filter.leavesOut("web-1", "01000000-0000-0000-0000-000000000000"))
+
+	filter = &FilterConfig{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+include:
+  - name: web-.*
+`), filter))
+	assert.True(t, filter.leavesOut("db-1", "02000000-0000-0000-0000-000000000000"))
+	assert.False(t, filter.leavesOut("web-1", "02000000-0000-0000-0000-000000000000"))
+}
@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeTimeoutHeader is set by Prometheus to the scrape timeout of the job.
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// scrapeContext returns a context that expires offset before the scrape
+// timeout announced by Prometheus, or after fallback when the header is
+// missing. A zero fallback means no deadline.
+func scrapeContext(r *http.Request, offset, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := fallback
+	if v := r.Header.Get(scrapeTimeoutHeader); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds > 0 {
+			timeout = time.Duration(seconds*float64(time.Second)) - offset
+			if timeout <= 0 {
+				timeout = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if timeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// scrapeCollector collects the exporter with the deadline of one scrape.
+type scrapeCollector struct {
+	ctx      context.Context
+	exporter *LibvirtExporter
+}
+
+func (s scrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	s.exporter.Describe(ch)
+}
+
+func (s scrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	s.exporter.collectLogged(s.ctx, ch)
+}
+
+// Handler returns an http.Handler serving the metrics of gatherer together
+// with the exporter's metrics. The exporter is collected with a deadline
+// derived from the X-Prometheus-Scrape-Timeout-Seconds header minus
+// timeoutOffset, so slow domains are abandoned instead of failing the scrape.
+// The exporter must not be registered with gatherer itself.
+func (e *LibvirtExporter) Handler(gatherer prometheus.Gatherer, timeoutOffset time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := scrapeContext(r, timeoutOffset, e.scrapeTimeout)
+		defer cancel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(scrapeCollector{ctx: ctx, exporter: e})
+		promhttp.HandlerFor(prometheus.Gatherers{gatherer, registry}, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
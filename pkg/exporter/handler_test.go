@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrapeContext(t *testing.T) {
+	deadlineIn := func(header string, offset, fallback time.Duration) (time.Duration, bool) {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			r.Header.Set(scrapeTimeoutHeader, header)
+		}
+		ctx, cancel := scrapeContext(r, offset, fallback)
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		return time.Until(deadline), ok
+	}
+
+	_, ok := deadlineIn("", time.Second, 0)
+	assert.False(t, ok)
+
+	d, ok := deadlineIn("", 0, 3*time.Second)
+	require.True(t, ok)
+	assert.InDelta(t, 3*time.Second, d, float64(time.Second))
+
+	d, ok = deadlineIn("10", time.Second, 3*time.Second)
+	require.True(t, ok)
+	assert.InDelta(t, 9*time.Second, d, float64(time.Second))
+
+	// an offset larger than the timeout is ignored
+	d, ok = deadlineIn("0.5", time.Second, 0)
+	require.True(t, ok)
+	assert.InDelta(t, 500*time.Millisecond, d, float64(500*time.Millisecond))
+
+	_, ok = deadlineIn("bogus", time.Second, 0)
+	assert.False(t, ok)
+}
+
+func TestCollectToSlice(t *testing.T) {
+	errTest := errors.New("test")
+	metrics, err := collectToSlice(func(ch chan<- prometheus.Metric) error {
+		ch <- prometheus.MustNewConstMetric(libvirtUpDesc, prometheus.GaugeValue, 1)
+		ch <- prometheus.MustNewConstMetric(libvirtDomainNumbers, prometheus.GaugeValue, 2)
+		return errTest
+	})
+	assert.Len(t, metrics, 2)
+	assert.ErrorIs(t, err, errTest)
+}
@@ -0,0 +1,162 @@
+package exporter
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtCollectorDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "collector", "duration_seconds"),
+		"Time the collector spent in the last scrape, summed over all domains for per-domain collectors.",
+		[]string{"collector"},
+		nil)
+	libvirtCollectorSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "collector", "success"),
+		"Whether the collector succeeded for every domain in the last scrape.",
+		[]string{"collector"},
+		nil)
+	libvirtErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "", "libvirt_errors_total"),
+		"Number of errors returned while collecting, by libvirt error code (virErrorNumber). Errors that did not come from libvirt have code \"other\".",
+		[]string{"code"},
+		nil)
+)
+
+// libvirtErrorCode returns the virErrorNumber of err as a label value.
+func libvirtErrorCode(err error) string {
+	var libvirtErr libvirt.Error
+	if errors.As(err, &libvirtErr) {
+		return strconv.FormatUint(uint64(libvirtErr.Code), 10)
+	}
+	return "other"
+}
+
+// isLibvirtErr reports whether err is a libvirt error with one of the codes.
+// libvirt.IsNotFound only matches missing domains.
+func isLibvirtErr(err error, codes ...libvirt.ErrorNumber) bool {
+	var libvirtErr libvirt.Error
+	if !errors.As(err, &libvirtErr) {
+		return false
+	}
+	for _, code := range codes {
+		if libvirtErr.Code == uint32(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCounter counts collection errors by libvirt error code over the
+// lifetime of the exporter.
+type errorCounter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func (c *errorCounter) add(err error) {
+	code := libvirtErrorCode(err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]uint64)
+	}
+	c.counts[code]++
+}
+
+func (c *errorCounter) collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for code, count := range c.counts {
+		ch <- prometheus.MustNewConstMetric(libvirtErrorsDesc, prometheus.CounterValue, float64(count), code)
+	}
+}
+
+// collectorStats records the duration and outcome of every collector during
+// one scrape. Collectors are wrapped by instrument, so the collectors
+// themselves stay unaware of it.
+type collectorStats struct {
+	errors *errorCounter
+
+	mu        sync.Mutex
+	names     []string
+	durations map[string]time.Duration
+	failed    map[string]bool
+}
+
+func newCollectorStats(set collectorSet, errors *errorCounter) *collectorStats {
+	s := &collectorStats{
+		errors:    errors,
+		durations: make(map[string]time.Duration, len(set)),
+		failed:    make(map[string]bool, len(set)),
+	}
+	for _, c := range set {
+		s.names = append(s.names, c.name)
+		s.durations[c.name] = 0
+	}
+	return s
+}
+
+// observe records one run of a collector. A domain that disappeared while it
+// was collected does not count as a failure.
+func (s *collectorStats) observe(name string, start time.Time, err error) {
+	duration := time.Since(start)
+	if libvirt.IsNotFound(err) {
+		err = nil
+	}
+	if err != nil {
+		s.errors.add(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durations[name] += duration
+	if err != nil {
+		s.failed[name] = true
+	}
+}
+
+// instrument returns copies of the collectors of set that report to s.
+func (s *collectorStats) instrument(set collectorSet) collectorSet {
+	instrumented := make(collectorSet, 0, len(set))
+	for _, c := range set {
+		c := *c
+		if domain := c.domain; domain != nil {
+			c.domain = func(ch chan<- prometheus.Metric, l *libvirt.Libvirt, d domainMeta, promLabels domainLabels, logger log.Logger) error {
+				start := time.Now()
+				err := domain(ch, l, d, promLabels, logger)
+				s.observe(c.name, start, err)
+				return err
+			}
+		}
+		if host := c.host; host != nil {
+			c.host = func(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) error {
+				start := time.Now()
+				err := host(ch, l, logger)
+				s.observe(c.name, start, err)
+				return err
+			}
+		}
+		instrumented = append(instrumented, &c)
+	}
+	return instrumented
+}
+
+func (s *collectorStats) collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range s.names {
+		success := 1.0
+		if s.failed[name] {
+			success = 0
+		}
+		ch <- prometheus.MustNewConstMetric(libvirtCollectorDurationDesc, prometheus.GaugeValue, s.durations[name].Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(libvirtCollectorSuccessDesc, prometheus.GaugeValue, success, name)
+	}
+}
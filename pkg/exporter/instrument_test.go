@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibvirtErrorCode(t *testing.T) {
+	err := fmt.Errorf("collect: %w", libvirt.Error{Code: uint32(libvirt.ErrNoDomain), Message: "no domain"})
+	assert.Equal(t, "42", libvirtErrorCode(err))
+	assert.Equal(t, "other", libvirtErrorCode(errors.New("boom")))
+
+	assert.True(t, isLibvirtErr(fmt.Errorf("wrapped: %w", libvirt.Error{Code: uint32(libvirt.ErrNoNetwork)}), libvirt.ErrNoStorageVol, libvirt.ErrNoNetwork))
+	assert.False(t, isLibvirtErr(err, libvirt.ErrNoNetwork))
+	assert.False(t, isLibvirtErr(errors.New("boom"), libvirt.ErrNoNetwork))
+}
+
+func TestCollectorStats(t *testing.T) {
+	set := collectorSet{
+		{name: "ok", host: func(chan<- prometheus.Metric, *libvirt.Libvirt, log.Logger) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		}},
+		{name: "broken", domain: func(chan<- prometheus.Metric, *libvirt.Libvirt, domainMeta, domainLabels, log.Logger) error {
+			return libvirt.Error{Code: uint32(libvirt.ErrOperationTimeout)}
+		}},
+	}
+	var errs errorCounter
+	stats := newCollectorStats(set, &errs)
+	instrumented := stats.instrument(set)
+
+	require.NoError(t, instrumented[0].host(nil, nil, log.NewNopLogger()))
+	require.Error(t, instrumented[1].domain(nil, nil, domainMeta{}, domainLabels{}, log.NewNopLogger()))
+	require.Error(t, instrumented[1].domain(nil, nil, domainMeta{}, domainLabels{}, log.NewNopLogger()))
+
+	metrics, _ := collectToSlice(func(ch chan<- prometheus.Metric) error {
+		stats.collect(ch)
+		errs.collect(ch)
+		return nil
+	})
+
+	// keyed by "<label value>/<metric>"
+	values := map[string]float64{}
+	for _, m := range metrics {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		key := pb.GetLabel()[0].GetValue() + "/" + descName(m.Desc())
+		if pb.Counter != nil {
+			values[key] = pb.GetCounter().GetValue()
+		} else {
+			values[key] = pb.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, 1.0, values["ok/success"])
+	assert.Equal(t, 0.0, values["broken/success"])
+	assert.Greater(t, values["ok/duration_seconds"], 0.0)
+	assert.Equal(t, 2.0, values["68/libvirt_errors_total"])
+}
+
+func descName(d *prometheus.Desc) string {
+	switch d {
+	case libvirtCollectorSuccessDesc:
+		return "success"
+	case libvirtCollectorDurationDesc:
+		return "duration_seconds"
+	case libvirtErrorsDesc:
+		return "libvirt_errors_total"
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// domainLabelValues are the labels that describe a domain, by name.
+var domainLabelValues = map[string]func(domain domainMeta) string{
+	"domain":          func(d domainMeta) string { return d.domainName },
+	"uuid":            func(d domainMeta) string { return formatUUID(d.libvirtDomain.UUID) },
+	"instance_name":   func(d domainMeta) string { return d.instanceName },
+	"instance_id":     func(d domainMeta) string { return d.instanceId },
+	"flavor_name":     func(d domainMeta) string { return d.flavorName },
+	"user_name":       func(d domainMeta) string { return d.userName },
+	"user_id":         func(d domainMeta) string { return d.userId },
+	"project_name":    func(d domainMeta) string { return d.projectName },
+	"project_id":      func(d domainMeta) string { return d.projectId },
+	"image_id":        func(d domainMeta) string { return d.imageId },
+	"package_version": func(d domainMeta) string { return d.packageVersion },
+	"os_type":         func(d domainMeta) string { return d.os_type },
+	"os_type_arch":    func(d domainMeta) string { return d.os_type_arch },
+	"os_type_machine": func(d domainMeta) string { return d.os_type_machine },
+	"namespace":       func(d domainMeta) string { return d.kubevirtNamespace },
+	"vmi":             func(d domainMeta) string { return d.kubevirtVMI },
+	"vmi_uid":         func(d domainMeta) string { return d.kubevirtUID },
+}
+
+// openstackInfoLabels are the labels of libvirt_domain_openstack_info that
+// are not already on every series.
+var openstackInfoLabels = []string{"instance_name", "instance_id", "flavor_name", "user_name", "user_id", "project_name", "project_id", "image_id", "package_version"}
+
+// LabelConfig chooses the labels that identify a domain.
+type LabelConfig struct {
+	// Domain are the labels on every per-domain series. They have to include
+	// domain or uuid.
+	Domain []string `yaml:"domain"`
+	// Info are the labels only on libvirt_domain_domain_info, to be joined
+	// on the Domain labels.
+	Info []string `yaml:"info"`
+	// Metadata are further labels of libvirt_domain_domain_info taken from
+	// the domain XML.
+	Metadata []MetadataLabel `yaml:"metadata"`
+}
+
+// DefaultLabelConfig is the label schema of earlier releases.
+var DefaultLabelConfig = LabelConfig{
+	Domain: []string{"domain", "instance_name", "project_id", "project_name"},
+	Info:   []string{"os_type", "os_type_arch", "os_type_machine", "namespace", "vmi"},
+}
+
+func (c LabelConfig) validate() error {
+	seen := make(map[string]bool, len(c.Domain)+len(c.Info))
+	for _, name := range append(append([]string(nil), c.Domain...), c.Info...) {
+		if _, ok := domainLabelValues[name]; !ok {
+			return fmt.Errorf("unknown domain label %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("domain label %q is given twice", name)
+		}
+		seen[name] = true
+	}
+	for _, label := range c.Metadata {
+		if err := label.validate(); err != nil {
+			return err
+		}
+		if seen[label.Label] {
+			return fmt.Errorf("domain label %q is given twice", label.Label)
+		}
+		seen[label.Label] = true
+	}
+	variable := variableLabelNames()
+	for _, name := range c.Domain {
+		if fqName, ok := variable[name]; ok {
+			return fmt.Errorf("domain label %q clashes with a label of %s", name, fqName)
+		}
+	}
+	for _, name := range c.Domain {
+		if name == "domain" || name == "uuid" {
+			return nil
+		}
+	}
+	return fmt.Errorf("the domain labels have to include domain or uuid")
+}
+
+// variableLabelNames returns the variable labels of all per-domain metrics
+// and the name of a metric having each. They cannot be domain labels.
+func variableLabelNames() map[string]string {
+	names := make(map[string]string)
+	add := func(descs []*domainDesc) {
+		for _, d := range descs {
+			for _, label := range d.variableLabels {
+				names[label] = d.fqName
+			}
+		}
+	}
+	add(baseDomainDescs)
+	add([]*domainDesc{libvirtDomainLifecycleEventsDesc})
+	for _, c := range collectors {
+		add(c.domainDescs)
+	}
+	return names
+}
+
+// domainDesc describes a per-domain metric. Its variable labels are the ones
+// besides those identifying the domain, which the labelSchema prepends.
+type domainDesc struct {
+	fqName         string
+	help           string
+	variableLabels []string
+	constLabels    prometheus.Labels
+}
+
+// newDomainDesc is prometheus.NewDesc for per-domain metrics.
+func newDomainDesc(fqName, help string, variableLabels []string, constLabels prometheus.Labels) *domainDesc {
+	return &domainDesc{
+		fqName:         fqName,
+		help:           help,
+		variableLabels: variableLabels,
+		constLabels:    constLabels,
+	}
+}
+
+// labelSchema builds the descriptors of the per-domain metrics for the
+// labels chosen by a LabelConfig.
+type labelSchema struct {
+	domain        []string
+	info          []string
+	openstackInfo []string
+	metadata      []MetadataLabel
+
+	mu    sync.Mutex
+	descs map[*domainDesc]*prometheus.Desc
+}
+
+func newLabelSchema(config LabelConfig) (*labelSchema, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	s := &labelSchema{
+		domain:   config.Domain,
+		info:     append([]string(nil), config.Info...),
+		metadata: config.Metadata,
+		descs:    make(map[*domainDesc]*prometheus.Desc),
+	}
+	for _, label := range config.Metadata {
+		s.info = append(s.info, label.Label)
+	}
+	onSeries := make(map[string]bool, len(config.Domain))
+	for _, name := range config.Domain {
+		onSeries[name] = true
+	}
+	for _, name := range openstackInfoLabels {
+		if !onSeries[name] {
+			s.openstackInfo = append(s.openstackInfo, name)
+		}
+	}
+	return s, nil
+}
+
+// desc returns the descriptor of d with the domain labels in front.
+func (s *labelSchema) desc(d *domainDesc) *prometheus.Desc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	desc, ok := s.descs[d]
+	if !ok {
+		labels := append(append([]string(nil), s.domain...), s.extraLabels(d)...)
+		desc = prometheus.NewDesc(d.fqName, d.help, labels, d.constLabels)
+		s.descs[d] = desc
+	}
+	return desc
+}
+
+// extraLabels returns the labels of d after the domain labels. The info
+// metrics take theirs from the schema.
+func (s *labelSchema) extraLabels(d *domainDesc) []string {
+	switch d {
+	case libvirtDomainInfoDesc:
+		return s.info
+	case libvirtDomainOpenstackInfoDesc:
+		return s.openstackInfo
+	}
+	return d.variableLabels
+}
+
+// labels returns the domain label values of domain.
+func (s *labelSchema) labels(domain domainMeta) domainLabels {
+	return domainLabels{schema: s, domain: domain, values: s.values(domain, s.domain)}
+}
+
+func (s *labelSchema) values(domain domainMeta, names []string) []string {
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		if value, ok := domainLabelValues[name]; ok {
+			values = append(values, value(domain))
+		} else {
+			values = append(values, domain.metadata[name])
+		}
+	}
+	return values
+}
+
+// extractMetadata returns the values of the metadata labels in the domain
+// XML. A nil schema has none.
+func (s *labelSchema) extractMetadata(xmlDesc string) (map[string]string, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return extractMetadata(xmlDesc, s.metadata)
+}
+
+func (s *labelSchema) describe(ch chan<- *prometheus.Desc, descs ...*domainDesc) {
+	for _, d := range descs {
+		ch <- s.desc(d)
+	}
+}
+
+// domainLabels are the domain label values of one domain.
+type domainLabels struct {
+	schema *labelSchema
+	domain domainMeta
+	values []string
+}
+
+// metric is prometheus.MustNewConstMetric for a per-domain metric, taking the
+// values of the labels of desc besides the domain labels.
+func (l domainLabels) metric(desc *domainDesc, valueType prometheus.ValueType, value float64, labelValues ...string) prometheus.Metric {
+	values := make([]string, 0, len(l.values)+len(labelValues))
+	values = append(append(values, l.values...), labelValues...)
+	return prometheus.MustNewConstMetric(l.schema.desc(desc), valueType, value, values...)
+}
+
+// infoMetrics returns libvirt_domain_domain_info and
+// libvirt_domain_openstack_info of the domain.
+func (l domainLabels) infoMetrics() []prometheus.Metric {
+	return []prometheus.Metric{
+		l.metric(libvirtDomainInfoDesc, prometheus.GaugeValue, 1, l.schema.values(l.domain, l.schema.info)...),
+		l.metric(libvirtDomainOpenstackInfoDesc, prometheus.GaugeValue, 1, l.schema.values(l.domain, l.schema.openstackInfo)...),
+	}
+}
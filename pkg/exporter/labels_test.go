@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+// metricLabels returns the labels of m by name.
+func metricLabels(t *testing.T, m prometheus.Metric) map[string]string {
+	var pb dto.Metric
+	require.NoError(t, m.Write(&pb))
+	labels := map[string]string{}
+	for _, lp := range pb.GetLabel() {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	return labels
+}
+
+func TestLabelSchema(t *testing.T) {
+	domain := domainMeta{
+		domainName:   "instance-0001",
+		instanceName: "web",
+		instanceId:   "0a000000-0000-0000-0000-000000000000",
+		flavorName:   "m1.small",
+		projectName:  "demo",
+		projectId:    "6f2a",
+		os_type:      "hvm",
+		libvirtDomain: libvirt.Domain{
+			Name: "instance-0001",
+			UUID: libvirt.UUID{10},
+		},
+	}
+
+	// the default schema keeps the labels of earlier releases, now with the
+	// project values on the right labels
+	schema, err := newLabelSchema(DefaultLabelConfig)
+	require.NoError(t, err)
+	labels := schema.labels(domain)
+	assert.Equal(t, map[string]string{
+		"domain":        "instance-0001",
+		"instance_name": "web",
+		"project_id":    "6f2a",
+		"project_name":  "demo",
+		"target_device": "vda",
+	}, metricLabels(t, labels.metric(libvirtDomainBlockStatsRdBytesDesc, prometheus.CounterValue, 1, "vda")))
+
+	schema, err = newLabelSchema(LabelConfig{
+		Domain: []string{"uuid"},
+		Info:   []string{"domain", "os_type"},
+	})
+	require.NoError(t, err)
+	labels = schema.labels(domain)
+	assert.Equal(t, map[string]string{
+		"uuid": "0a000000-0000-0000-0000-000000000000",
+	}, metricLabels(t, labels.metric(libvirtDomainInfoMemoryDesc, prometheus.GaugeValue, 1)))
+	assert.Same(t, schema.desc(libvirtDomainInfoMemoryDesc), schema.desc(libvirtDomainInfoMemoryDesc))
+
+	info := labels.infoMetrics()
+	require.Len(t, info, 2)
+	assert.Equal(t, map[string]string{
+		"uuid":    "0a000000-0000-0000-0000-000000000000",
+		"domain":  "instance-0001",
+		"os_type": "hvm",
+	}, metricLabels(t, info[0]))
+	assert.Equal(t, "m1.small", metricLabels(t, info[1])["flavor_name"])
+	assert.Len(t, metricLabels(t, info[1]), 1+len(openstackInfoLabels))
+
+	for _, config := range []LabelConfig{
+		{Domain: []string{"instance_name"}},
+		{Domain: []string{"domain", "bogus"}},
+		{Domain: []string{"domain"}, Info: []string{"domain"}},
+		// libvirt_domain_ovirt_disk_info has an image_id label of its own
+		{Domain: []string{"domain", "image_id"}},
+	} {
+		_, err := newLabelSchema(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestKubeVirtLabels(t *testing.T) {
+	var schema libvirt_schema.Domain
+	require.NoError(t, xml.Unmarshal([]byte(`<domain type="kvm">
+  <name>default_testvmi</name>
+  <metadata>
+    <kubevirt xmlns="http://kubevirt.io">
+      <uid>b6d3d4c4-3a4a-4b1e-9d2f-6c5e1c0e2a11</uid>
+    </kubevirt>
+  </metadata>
+</domain>`), &schema))
+	domain := newDomainMeta(libvirt.Domain{Name: "default_testvmi"}, schema)
+
+	labels, err := newLabelSchema(LabelConfig{Domain: []string{"domain"}, Info: []string{"namespace", "vmi", "vmi_uid"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"domain":    "default_testvmi",
+		"namespace": "default",
+		"vmi":       "testvmi",
+		"vmi_uid":   "b6d3d4c4-3a4a-4b1e-9d2f-6c5e1c0e2a11",
+	}, metricLabels(t, labels.labels(domain).infoMetrics()[0]))
+
+	// other domains with an underscore in their name are not split
+	domain = newDomainMeta(libvirt.Domain{Name: "default_testvmi"}, libvirt_schema.Domain{})
+	assert.Empty(t, domain.kubevirtNamespace)
+}
@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// MetadataLabel exposes a value of the domain XML as a label of
+// libvirt_domain_domain_info.
+type MetadataLabel struct {
+	// Label is the name of the label.
+	Label string `yaml:"label"`
+	// Path is the slash-separated path of an element below <domain>, e.g.
+	// title or metadata/tags/team. A last step of the form @name selects an
+	// attribute of the element instead of its text.
+	Path string `yaml:"path"`
+	// Namespace is the XML namespace URI the element directly below
+	// <metadata> has to be in, to tell apart the data of orchestrators using
+	// the same element names.
+	Namespace string `yaml:"namespace"`
+}
+
+func (m MetadataLabel) validate() error {
+	if !model.LabelName(m.Label).IsValid() {
+		return fmt.Errorf("invalid metadata label name %q", m.Label)
+	}
+	if _, ok := domainLabelValues[m.Label]; ok {
+		return fmt.Errorf("metadata label %q is a built-in label", m.Label)
+	}
+	steps := m.steps()
+	if len(steps) == 0 {
+		return fmt.Errorf("metadata label %q has no path", m.Label)
+	}
+	for i, step := range steps {
+		if step == "" || (strings.HasPrefix(step, "@") && i != len(steps)-1) {
+			return fmt.Errorf("invalid path %q of metadata label %q", m.Path, m.Label)
+		}
+	}
+	if m.Namespace != "" && (len(steps) < 2 || steps[0] != "metadata") {
+		return fmt.Errorf("metadata label %q has a namespace but its path is not below metadata", m.Label)
+	}
+	return nil
+}
+
+func (m MetadataLabel) steps() []string {
+	path := strings.Trim(m.Path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// xmlNode is an element of a parsed XML document.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     strings.Builder
+	children []*xmlNode
+}
+
+// parseXMLTree parses an XML document into a tree of its elements.
+func parseXMLTree(doc string) (*xmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	var (
+		root  *xmlNode
+		stack []*xmlNode
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("empty XML document")
+	}
+	return root, nil
+}
+
+// lookup returns the value of the first element or attribute that m selects
+// below root.
+func (m MetadataLabel) lookup(root *xmlNode) (string, bool) {
+	steps := m.steps()
+	nodes := []*xmlNode{root}
+	for i, step := range steps {
+		if attr, ok := strings.CutPrefix(step, "@"); ok {
+			for _, node := range nodes {
+				for _, a := range node.attrs {
+					if a.Name.Local == attr {
+						return a.Value, true
+					}
+				}
+			}
+			return "", false
+		}
+
+		var next []*xmlNode
+		for _, node := range nodes {
+			for _, child := range node.children {
+				if child.name.Local != step {
+					continue
+				}
+				// the element below <metadata> has to be in the namespace
+				if i == 1 && m.Namespace != "" && child.name.Space != m.Namespace {
+					continue
+				}
+				next = append(next, child)
+			}
+		}
+		if len(next) == 0 {
+			return "", false
+		}
+		nodes = next
+	}
+	return strings.TrimSpace(nodes[0].text.String()), true
+}
+
+// extractMetadata returns the values of labels in the domain XML. Labels
+// whose path does not exist in the XML are left out.
+func extractMetadata(xmlDesc string, labels []MetadataLabel) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	root, err := parseXMLTree(xmlDesc)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(labels))
+	for _, label := range labels {
+		if value, ok := label.lookup(root); ok {
+			values[label.Label] = value
+		}
+	}
+	return values, nil
+}
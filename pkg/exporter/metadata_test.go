@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const metadataTestXML = `<domain type="kvm">
+  <name>web-1</name>
+  <title>Web frontend</title>
+  <description>
+    serves www
+  </description>
+  <metadata>
+    <acme:tags xmlns:acme="http://acme.example.com/tags/1.0">
+      <acme:team>storefront</acme:team>
+      <acme:cost center="42"/>
+    </acme:tags>
+    <other:tags xmlns:other="http://other.example.com/">
+      <other:team>platform</other:team>
+    </other:tags>
+  </metadata>
+</domain>`
+
+func TestExtractMetadata(t *testing.T) {
+	labels := []MetadataLabel{
+		{Label: "title", Path: "title"},
+		{Label: "description", Path: "/description"},
+		{Label: "team", Path: "metadata/tags/team", Namespace: "http://acme.example.com/tags/1.0"},
+		{Label: "other_team", Path: "metadata/tags/team", Namespace: "http://other.example.com/"},
+		{Label: "cost_center", Path: "metadata/tags/cost/@center"},
+		{Label: "missing", Path: "metadata/tags/owner"},
+	}
+	for _, label := range labels {
+		require.NoError(t, label.validate())
+	}
+
+	values, err := extractMetadata(metadataTestXML, labels)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"title":       "Web frontend",
+		"description": "serves www",
+		"team":        "storefront",
+		"other_team":  "platform",
+		"cost_center": "42",
+	}, values)
+
+	_, err = extractMetadata("<domain><name>", labels)
+	assert.Error(t, err)
+
+	for _, label := range []MetadataLabel{
+		{Label: "0team", Path: "title"},
+		{Label: "domain", Path: "title"},
+		{Label: "team", Path: ""},
+		{Label: "team", Path: "metadata/@id/team"},
+		{Label: "team", Path: "title", Namespace: "http://acme.example.com/tags/1.0"},
+	} {
+		assert.Error(t, label.validate(), label)
+	}
+}
+
+func TestLabelSchemaMetadata(t *testing.T) {
+	schema, err := newLabelSchema(LabelConfig{
+		Domain:   []string{"domain"},
+		Metadata: []MetadataLabel{{Label: "team", Path: "metadata/tags/team"}},
+	})
+	require.NoError(t, err)
+
+	domain := domainMeta{domainName: "web-1"}
+	domain.metadata, err = schema.extractMetadata(metadataTestXML)
+	require.NoError(t, err)
+
+	info := schema.labels(domain).infoMetrics()
+	assert.Equal(t, map[string]string{"domain": "web-1", "team": "storefront"}, metricLabels(t, info[0]))
+
+	_, err = newLabelSchema(LabelConfig{
+		Domain:   []string{"domain"},
+		Metadata: []MetadataLabel{{Label: "team", Path: "a"}, {Label: "team", Path: "b"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"net/netip"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+var (
+	libvirtNetworkInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "info"),
+		"Bridge of the virtual network as label.",
+		[]string{"network", "bridge"},
+		nil)
+	libvirtNetworkActiveDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "active"),
+		"Whether the virtual network is active.",
+		[]string{"network"},
+		nil)
+	libvirtNetworkAutostartDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "autostart"),
+		"Whether the virtual network is started when libvirtd starts.",
+		[]string{"network"},
+		nil)
+	libvirtNetworkPersistentDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "persistent"),
+		"Whether the virtual network is persistent, rather than transient.",
+		[]string{"network"},
+		nil)
+	libvirtNetworkDHCPRangeSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "dhcp_range_addresses"),
+		"Number of addresses in the DHCP ranges of the virtual network, by address family.",
+		[]string{"network", "family"},
+		nil)
+	libvirtNetworkDHCPLeasesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "dhcp_leases"),
+		"Number of DHCP leases of the active virtual network, by address family.",
+		[]string{"network", "family"},
+		nil)
+	libvirtNetworkDHCPLeaseEarliestExpiryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "dhcp_lease_earliest_expiry_timestamp_seconds"),
+		"Unix time the first expiring DHCP lease of the virtual network expires at, by address family.",
+		[]string{"network", "family"},
+		nil)
+	libvirtNetworkDHCPLeaseLatestExpiryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "network", "dhcp_lease_latest_expiry_timestamp_seconds"),
+		"Unix time the last expiring DHCP lease of the virtual network expires at, by address family.",
+		[]string{"network", "family"},
+		nil)
+)
+
+func init() {
+	registerCollector(&collector{
+		name: "network",
+		descs: []*prometheus.Desc{
+			libvirtNetworkInfoDesc,
+			libvirtNetworkActiveDesc,
+			libvirtNetworkAutostartDesc,
+			libvirtNetworkPersistentDesc,
+			libvirtNetworkDHCPRangeSizeDesc,
+			libvirtNetworkDHCPLeasesDesc,
+			libvirtNetworkDHCPLeaseEarliestExpiryDesc,
+			libvirtNetworkDHCPLeaseLatestExpiryDesc,
+		},
+		host: CollectNetworks,
+	}, true)
+}
+
+// CollectNetworks collects the state and DHCP leases of the virtual
+// networks.
+func CollectNetworks(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error) {
+	var networks []libvirt.Network
+	if networks, _, err = l.ConnectListAllNetworks(1, 0); err != nil {
+		_ = level.Error(logger).Log("err", "failed to collect networks", "msg", err)
+		return err
+	}
+	// one failing network does not hide the others
+	for _, network := range networks {
+		if networkErr := CollectNetworkInfo(ch, l, network, logger); isLibvirtErr(networkErr, libvirt.ErrNoNetwork) {
+			// undefined since it was listed
+			continue
+		} else if networkErr != nil {
+			_ = level.Error(logger).Log("err", "failed to collect network info", "network", network.Name, "msg", networkErr)
+			err = networkErr
+		}
+	}
+	return err
+}
+
+func CollectNetworkInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, network libvirt.Network, logger log.Logger) (err error) {
+	var active, autostart, persistent int32
+	if active, err = l.NetworkIsActive(network); err != nil {
+		return err
+	}
+	if autostart, err = l.NetworkGetAutostart(network); err != nil {
+		return err
+	}
+	if persistent, err = l.NetworkIsPersistent(network); err != nil {
+		return err
+	}
+	xmlDesc, err := l.NetworkGetXMLDesc(network, 0)
+	if err != nil {
+		return err
+	}
+	var schema libvirt_schema.Network
+	if err = xml.Unmarshal([]byte(xmlDesc), &schema); err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(libvirtNetworkInfoDesc, prometheus.GaugeValue, 1, network.Name, schema.Bridge.Name)
+	ch <- prometheus.MustNewConstMetric(libvirtNetworkActiveDesc, prometheus.GaugeValue, float64(active), network.Name)
+	ch <- prometheus.MustNewConstMetric(libvirtNetworkAutostartDesc, prometheus.GaugeValue, float64(autostart), network.Name)
+	ch <- prometheus.MustNewConstMetric(libvirtNetworkPersistentDesc, prometheus.GaugeValue, float64(persistent), network.Name)
+
+	// the families are counted apart, an exhausted IPv4 range would be hidden
+	// by the IPv6 ones
+	rangeSizes := make(map[string]float64)
+	for _, ip := range schema.IPs {
+		for _, r := range ip.DHCP.Ranges {
+			family, size, err := dhcpRangeSize(r)
+			if err != nil {
+				_ = level.Warn(logger).Log("warn", "invalid DHCP range", "network", network.Name, "msg", err)
+				continue
+			}
+			rangeSizes[family] += size
+		}
+	}
+	for family, size := range rangeSizes {
+		ch <- prometheus.MustNewConstMetric(libvirtNetworkDHCPRangeSizeDesc, prometheus.GaugeValue, size, network.Name, family)
+	}
+
+	// leases are only served by active networks
+	if active != 1 {
+		return nil
+	}
+	leases, _, err := l.NetworkGetDhcpLeases(network, nil, 1, 0)
+	if err != nil {
+		return err
+	}
+	families := summarizeLeases(leases)
+	for family := range rangeSizes {
+		if _, ok := families[family]; !ok {
+			families[family] = &leaseSummary{}
+		}
+	}
+	// one series per lease would churn on busy networks
+	for family, summary := range families {
+		ch <- prometheus.MustNewConstMetric(libvirtNetworkDHCPLeasesDesc, prometheus.GaugeValue, float64(summary.count), network.Name, family)
+		if summary.earliest > 0 {
+			ch <- prometheus.MustNewConstMetric(libvirtNetworkDHCPLeaseEarliestExpiryDesc, prometheus.GaugeValue, float64(summary.earliest), network.Name, family)
+			ch <- prometheus.MustNewConstMetric(libvirtNetworkDHCPLeaseLatestExpiryDesc, prometheus.GaugeValue, float64(summary.latest), network.Name, family)
+		}
+	}
+	return nil
+}
+
+// leaseSummary is the number of DHCP leases of an address family and when
+// the first and the last of those expiring expire, 0 if none does.
+type leaseSummary struct {
+	count            int
+	earliest, latest int64
+}
+
+// summarizeLeases summarizes the DHCP leases by address family.
+func summarizeLeases(leases []libvirt.NetworkDhcpLease) map[string]*leaseSummary {
+	families := make(map[string]*leaseSummary)
+	for _, lease := range leases {
+		family := leaseFamily(lease)
+		summary, ok := families[family]
+		if !ok {
+			summary = &leaseSummary{}
+			families[family] = summary
+		}
+		summary.count++
+		// infinite leases have no expiry time
+		if lease.Expirytime <= 0 {
+			continue
+		}
+		if summary.earliest == 0 || lease.Expirytime < summary.earliest {
+			summary.earliest = lease.Expirytime
+		}
+		if lease.Expirytime > summary.latest {
+			summary.latest = lease.Expirytime
+		}
+	}
+	return families
+}
+
+// leaseFamily returns the address family of a DHCP lease, ipv4 or ipv6.
+func leaseFamily(lease libvirt.NetworkDhcpLease) string {
+	if libvirt.IPAddrType(lease.Type) == libvirt.IPAddrTypeIpv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// dhcpRangeSize returns the address family, ipv4 or ipv6, and the number of
+// addresses from the start to the end of the range.
+func dhcpRangeSize(r libvirt_schema.NetworkDHCPRange) (string, float64, error) {
+	start, err := netip.ParseAddr(r.Start)
+	if err != nil {
+		return "", 0, err
+	}
+	end, err := netip.ParseAddr(r.End)
+	if err != nil {
+		return "", 0, err
+	}
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return "", 0, fmt.Errorf("invalid range from %s to %s", start, end)
+	}
+	family := "ipv6"
+	if start.Is4() {
+		family = "ipv4"
+	}
+	s, e := start.As16(), end.As16()
+	size := new(big.Int).Sub(new(big.Int).SetBytes(e[:]), new(big.Int).SetBytes(s[:]))
+	f, _ := new(big.Float).SetInt(size.Add(size, big.NewInt(1))).Float64()
+	return family, f, nil
+}
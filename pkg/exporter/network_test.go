@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+func TestDHCPRangeSize(t *testing.T) {
+	for _, tc := range []struct {
+		start, end, family string
+		size               float64
+	}{
+		{"192.168.122.2", "192.168.122.254", "ipv4", 253},
+		{"10.0.0.10", "10.0.0.10", "ipv4", 1},
+		{"10.0.0.200", "10.0.1.9", "ipv4", 66},
+		{"2001:db8:ca2:2::100", "2001:db8:ca2:2::1ff", "ipv6", 256},
+	} {
+		family, size, err := dhcpRangeSize(libvirt_schema.NetworkDHCPRange{Start: tc.start, End: tc.end})
+		assert.NoError(t, err, tc.start)
+		assert.Equal(t, tc.family, family, tc.start)
+		assert.Equal(t, tc.size, size, tc.start)
+	}
+
+	for _, r := range []libvirt_schema.NetworkDHCPRange{
+		{Start: "192.168.122.254", End: "192.168.122.2"},
+		{Start: "192.168.122.2", End: "2001:db8::1"},
+		{Start: "192.168.122", End: "192.168.122.254"},
+	} {
+		_, _, err := dhcpRangeSize(r)
+		assert.Error(t, err, r.Start)
+	}
+}
+
+func TestLeaseFamily(t *testing.T) {
+	assert.Equal(t, "ipv4", leaseFamily(libvirt.NetworkDhcpLease{Type: int32(libvirt.IPAddrTypeIpv4), Ipaddr: "192.168.122.10"}))
+	assert.Equal(t, "ipv6", leaseFamily(libvirt.NetworkDhcpLease{Type: int32(libvirt.IPAddrTypeIpv6), Ipaddr: "2001:db8:ca2:2::110"}))
+}
+
+func TestSummarizeLeases(t *testing.T) {
+	lease := func(family libvirt.IPAddrType, expiry int64) libvirt.NetworkDhcpLease {
+		return libvirt.NetworkDhcpLease{Type: int32(family), Expirytime: expiry}
+	}
+	families := summarizeLeases([]libvirt.NetworkDhcpLease{
+		lease(libvirt.IPAddrTypeIpv4, 1700003600),
+		lease(libvirt.IPAddrTypeIpv4, 1700000000),
+		lease(libvirt.IPAddrTypeIpv4, 0),
+		lease(libvirt.IPAddrTypeIpv6, 0),
+	})
+	assert.Equal(t, map[string]*leaseSummary{
+		"ipv4": {count: 3, earliest: 1700000000, latest: 1700003600},
+		// only infinite leases
+		"ipv6": {count: 1},
+	}, families)
+}
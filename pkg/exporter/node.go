@@ -0,0 +1,212 @@
+package exporter
+
+import (
+	"strconv"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtNodeInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "info"),
+		"Model of the host CPUs as label.",
+		[]string{"cpu_model"},
+		nil)
+	libvirtNodeCPUsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpus"),
+		"Number of active host CPUs.",
+		nil,
+		nil)
+	libvirtNodeCPUFrequencyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpu_frequency_megahertz"),
+		"Expected frequency of the host CPUs in MHz.",
+		nil,
+		nil)
+	libvirtNodeNUMACellsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "numa_cells"),
+		"Number of NUMA cells of the host, 1 for uniform memory access or unusual topologies.",
+		nil,
+		nil)
+	libvirtNodeCPUSocketsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpu_sockets_per_numa_cell"),
+		"Number of CPU sockets per NUMA cell of the host.",
+		nil,
+		nil)
+	libvirtNodeCPUCoresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpu_cores_per_socket"),
+		"Number of CPU cores per socket of the host.",
+		nil,
+		nil)
+	libvirtNodeCPUThreadsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpu_threads_per_core"),
+		"Number of CPU threads per core of the host.",
+		nil,
+		nil)
+	libvirtNodeCPUSecondsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "cpu_seconds_total"),
+		"Seconds the host CPUs spent in each mode (kernel, user, idle, iowait).",
+		[]string{"cpu", "mode"},
+		nil)
+	libvirtNodeMemoryTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "memory_total_bytes"),
+		"Total memory of the host in bytes.",
+		nil,
+		nil)
+	libvirtNodeMemoryFreeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "memory_free_bytes"),
+		"Free memory of the host in bytes.",
+		nil,
+		nil)
+	libvirtNodeMemoryBuffersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "memory_buffers_bytes"),
+		"Memory of the host used for block device buffers in bytes.",
+		nil,
+		nil)
+	libvirtNodeMemoryCachedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "memory_cached_bytes"),
+		"Memory of the host used for the page cache in bytes.",
+		nil,
+		nil)
+	libvirtNodeCellFreeMemoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "node", "numa_cell_memory_free_bytes"),
+		"Free memory of a NUMA cell of the host in bytes.",
+		[]string{"cell"},
+		nil)
+
+	// nodeCPUModes are the NodeGetCPUStats fields exported, in nanoseconds.
+	nodeCPUModes = []string{"kernel", "user", "idle", "iowait"}
+)
+
+func init() {
+	registerCollector(&collector{
+		name: "node",
+		descs: []*prometheus.Desc{
+			libvirtNodeInfoDesc,
+			libvirtNodeCPUsDesc,
+			libvirtNodeCPUFrequencyDesc,
+			libvirtNodeNUMACellsDesc,
+			libvirtNodeCPUSocketsDesc,
+			libvirtNodeCPUCoresDesc,
+			libvirtNodeCPUThreadsDesc,
+			libvirtNodeCPUSecondsDesc,
+			libvirtNodeMemoryTotalDesc,
+			libvirtNodeMemoryFreeDesc,
+			libvirtNodeMemoryBuffersDesc,
+			libvirtNodeMemoryCachedDesc,
+			libvirtNodeCellFreeMemoryDesc,
+		},
+		host: CollectNode,
+	}, true)
+}
+
+// CollectNode collects the CPU and memory metrics of the hypervisor host.
+func CollectNode(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error) {
+	model, _, cpus, mhz, nodes, sockets, cores, threads, err := l.NodeGetInfo()
+	if err != nil {
+		_ = level.Error(logger).Log("err", "failed to get NodeGetInfo", "msg", err)
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtNodeInfoDesc, prometheus.GaugeValue, 1, nodeCPUModel(model))
+	ch <- prometheus.MustNewConstMetric(libvirtNodeCPUsDesc, prometheus.GaugeValue, float64(cpus))
+	ch <- prometheus.MustNewConstMetric(libvirtNodeCPUFrequencyDesc, prometheus.GaugeValue, float64(mhz))
+	ch <- prometheus.MustNewConstMetric(libvirtNodeNUMACellsDesc, prometheus.GaugeValue, float64(nodes))
+	ch <- prometheus.MustNewConstMetric(libvirtNodeCPUSocketsDesc, prometheus.GaugeValue, float64(sockets))
+	ch <- prometheus.MustNewConstMetric(libvirtNodeCPUCoresDesc, prometheus.GaugeValue, float64(cores))
+	ch <- prometheus.MustNewConstMetric(libvirtNodeCPUThreadsDesc, prometheus.GaugeValue, float64(threads))
+
+	// CPUs can be offline, so their IDs are not simply 0 to cpus-1
+	cpumap, _, present, err := l.NodeGetCPUMap(1, 0, 0)
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get NodeGetCPUMap", "msg", err)
+		return err
+	}
+	// the number of fields is the same for every CPU
+	_, cpuParams, err := l.NodeGetCPUStats(int32(libvirt.NodeCPUStatsAllCpus), 0, 0)
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get NodeGetCPUStats", "msg", err)
+		return err
+	}
+	for _, cpu := range onlineCPUs(cpumap, present) {
+		params, _, err := l.NodeGetCPUStats(cpu, cpuParams, 0)
+		if err != nil {
+			// e.g. taken offline since the CPU map was read
+			_ = level.Warn(logger).Log("warn", "failed to get NodeGetCPUStats", "cpu", cpu, "msg", err)
+			continue
+		}
+		values := make(map[string]uint64, len(params))
+		for _, p := range params {
+			values[p.Field] = p.Value
+		}
+		for _, mode := range nodeCPUModes {
+			if value, ok := values[mode]; ok {
+				ch <- prometheus.MustNewConstMetric(libvirtNodeCPUSecondsDesc, prometheus.CounterValue, float64(value)/1e9, strconv.Itoa(int(cpu)), mode)
+			}
+		}
+	}
+
+	_, nparams, err := l.NodeGetMemoryStats(0, int32(libvirt.NodeMemoryStatsAllCells), 0)
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get NodeGetMemoryStats", "msg", err)
+		return err
+	}
+	memStats, _, err := l.NodeGetMemoryStats(nparams, int32(libvirt.NodeMemoryStatsAllCells), 0)
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get NodeGetMemoryStats", "msg", err)
+		return err
+	}
+	for _, stat := range memStats {
+		// the memory stats are in KiB
+		switch stat.Field {
+		case "total":
+			ch <- prometheus.MustNewConstMetric(libvirtNodeMemoryTotalDesc, prometheus.GaugeValue, float64(stat.Value)*1024)
+		case "buffers":
+			ch <- prometheus.MustNewConstMetric(libvirtNodeMemoryBuffersDesc, prometheus.GaugeValue, float64(stat.Value)*1024)
+		case "cached":
+			ch <- prometheus.MustNewConstMetric(libvirtNodeMemoryCachedDesc, prometheus.GaugeValue, float64(stat.Value)*1024)
+		}
+	}
+
+	free, err := l.NodeGetFreeMemory()
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get NodeGetFreeMemory", "msg", err)
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtNodeMemoryFreeDesc, prometheus.GaugeValue, float64(free))
+
+	cells, err := l.NodeGetCellsFreeMemory(0, nodes)
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get NodeGetCellsFreeMemory", "msg", err)
+		return err
+	}
+	for cell, free := range cells {
+		ch <- prometheus.MustNewConstMetric(libvirtNodeCellFreeMemoryDesc, prometheus.GaugeValue, float64(free), strconv.Itoa(cell))
+	}
+	return nil
+}
+
+// onlineCPUs returns the IDs of the CPUs set in the bitmap of NodeGetCPUMap,
+// which has a bit for each of the present CPUs.
+func onlineCPUs(cpumap []byte, present int32) []int32 {
+	var cpus []int32
+	for cpu := int32(0); cpu < present && int(cpu/8) < len(cpumap); cpu++ {
+		if cpumap[cpu/8]&(1<<(cpu%8)) != 0 {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// nodeCPUModel returns the NUL-terminated CPU model of NodeGetInfo.
+func nodeCPUModel(model [32]int8) string {
+	b := make([]byte, 0, len(model))
+	for _, c := range model {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
@@ -0,0 +1,30 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeCPUModel(t *testing.T) {
+	var model [32]int8
+	for i, c := range "x86_64" {
+		model[i] = int8(c)
+	}
+	assert.Equal(t, "x86_64", nodeCPUModel(model))
+
+	// a model filling the whole array has no terminating NUL
+	for i := range model {
+		model[i] = 'a'
+	}
+	assert.Len(t, nodeCPUModel(model), 32)
+}
+
+func TestOnlineCPUs(t *testing.T) {
+	// CPUs 0, 1, 3 and 9 online, 2 and 4-8 offline
+	assert.Equal(t, []int32{0, 1, 3, 9}, onlineCPUs([]byte{0x0b, 0x02}, 10))
+	// bits beyond the present CPUs are ignored
+	assert.Equal(t, []int32{0, 1}, onlineCPUs([]byte{0xff}, 2))
+	// a short map does not read past its end
+	assert.Equal(t, []int32{0}, onlineCPUs([]byte{0x01}, 16))
+}
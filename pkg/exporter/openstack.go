@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// novaTimeLayout is the layout of the UTC creation time Nova records.
+const novaTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	libvirtDomainOpenstackFlavorMemoryDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "openstack", "flavor_memory_bytes"),
+		"Memory of the Nova flavor of the instance in bytes.",
+		nil,
+		nil)
+	libvirtDomainOpenstackFlavorDiskDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "openstack", "flavor_disk_bytes"),
+		"Root disk size of the Nova flavor of the instance in bytes.",
+		nil,
+		nil)
+	libvirtDomainOpenstackFlavorSwapDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "openstack", "flavor_swap_bytes"),
+		"Swap disk size of the Nova flavor of the instance in bytes.",
+		nil,
+		nil)
+	libvirtDomainOpenstackFlavorEphemeralDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "openstack", "flavor_ephemeral_bytes"),
+		"Ephemeral disk size of the Nova flavor of the instance in bytes.",
+		nil,
+		nil)
+	libvirtDomainOpenstackFlavorVcpusDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "openstack", "flavor_vcpus"),
+		"Number of virtual CPUs of the Nova flavor of the instance.",
+		nil,
+		nil)
+	libvirtDomainOpenstackCreationTimeDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "openstack", "creation_timestamp_seconds"),
+		"Unix time the Nova instance was created at.",
+		nil,
+		nil)
+
+	openstackDescs = []*domainDesc{
+		libvirtDomainOpenstackFlavorMemoryDesc,
+		libvirtDomainOpenstackFlavorDiskDesc,
+		libvirtDomainOpenstackFlavorSwapDesc,
+		libvirtDomainOpenstackFlavorEphemeralDesc,
+		libvirtDomainOpenstackFlavorVcpusDesc,
+		libvirtDomainOpenstackCreationTimeDesc,
+	}
+)
+
+// openstackMetrics returns the flavor sizes and the creation time of the
+// domain, if Nova runs it.
+func (l domainLabels) openstackMetrics() []prometheus.Metric {
+	instance := l.domain.libvirtSchema.Metadata.NovaInstance
+	if instance.XMLName.Local == "" {
+		return nil
+	}
+	flavor := instance.Flavor
+	metrics := []prometheus.Metric{
+		l.metric(libvirtDomainOpenstackFlavorMemoryDesc, prometheus.GaugeValue, float64(flavor.Memory)*1024*1024),
+		l.metric(libvirtDomainOpenstackFlavorDiskDesc, prometheus.GaugeValue, float64(flavor.Disk)*1024*1024*1024),
+		l.metric(libvirtDomainOpenstackFlavorSwapDesc, prometheus.GaugeValue, float64(flavor.Swap)*1024*1024),
+		l.metric(libvirtDomainOpenstackFlavorEphemeralDesc, prometheus.GaugeValue, float64(flavor.Ephemeral)*1024*1024*1024),
+		l.metric(libvirtDomainOpenstackFlavorVcpusDesc, prometheus.GaugeValue, float64(flavor.VCPUs)),
+	}
+	if created, err := time.Parse(novaTimeLayout, instance.CreationTime); err == nil {
+		metrics = append(metrics, l.metric(libvirtDomainOpenstackCreationTimeDesc, prometheus.GaugeValue, float64(created.Unix())))
+	}
+	return metrics
+}
@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+const novaTestXML = `<domain type='kvm'>
+  <name>instance-00000212</name>
+  <uuid>a6b57d2e-dad0-4860-9104-6eb072935126</uuid>
+  <metadata>
+    <nova:instance xmlns:nova="http://openstack.org/xmlns/libvirt/nova/1.0">
+      <nova:package version="15.0.4-1.el7"/>
+      <nova:name>LqnyzNfe</nova:name>
+      <nova:creationTime>2018-04-23 07:08:32</nova:creationTime>
+      <nova:flavor name="c1.micro">
+        <nova:memory>1024</nova:memory>
+        <nova:disk>20</nova:disk>
+        <nova:swap>512</nova:swap>
+        <nova:ephemeral>0</nova:ephemeral>
+        <nova:vcpus>1</nova:vcpus>
+      </nova:flavor>
+      <nova:root type="image" uuid="674e506f-1791-435a-a85f-f983d7c9fef6"/>
+    </nova:instance>
+  </metadata>
+</domain>`
+
+func TestOpenstackMetrics(t *testing.T) {
+	schema, err := newLabelSchema(LabelConfig{Domain: []string{"domain"}})
+	require.NoError(t, err)
+
+	var domainSchema libvirt_schema.Domain
+	require.NoError(t, xml.Unmarshal([]byte(novaTestXML), &domainSchema))
+	labels := schema.labels(newDomainMeta(libvirt.Domain{Name: "instance-00000212"}, domainSchema))
+
+	values := map[string]float64{}
+	for _, m := range labels.openstackMetrics() {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		values[m.Desc().String()] = pb.GetGauge().GetValue()
+	}
+	desc := func(d *domainDesc) string { return schema.desc(d).String() }
+	assert.Equal(t, map[string]float64{
+		desc(libvirtDomainOpenstackFlavorMemoryDesc):    1 << 30,
+		desc(libvirtDomainOpenstackFlavorDiskDesc):      20 << 30,
+		desc(libvirtDomainOpenstackFlavorSwapDesc):      512 << 20,
+		desc(libvirtDomainOpenstackFlavorEphemeralDesc): 0,
+		desc(libvirtDomainOpenstackFlavorVcpusDesc):     1,
+		desc(libvirtDomainOpenstackCreationTimeDesc):    1524467312,
+	}, values)
+
+	info := labels.infoMetrics()[1]
+	assert.Equal(t, "674e506f-1791-435a-a85f-f983d7c9fef6", metricLabels(t, info)["image_id"])
+	assert.Equal(t, "15.0.4-1.el7", metricLabels(t, info)["package_version"])
+
+	other := newDomainMeta(libvirt.Domain{Name: "web-1"}, libvirt_schema.Domain{})
+	assert.Empty(t, schema.labels(other).openstackMetrics())
+}
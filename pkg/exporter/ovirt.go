@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtDomainOvirtInfoDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "", "ovirt_info"),
+		"oVirt metadata labels for the domain, only for domains of oVirt/RHV VMs.",
+		[]string{"vm_id", "cluster_version"},
+		nil)
+	libvirtDomainOvirtDiskInfoDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "", "ovirt_disk_info"),
+		"oVirt storage of a disk of the domain, only for domains of oVirt/RHV VMs.",
+		[]string{"target_device", "storage_domain_id", "storage_pool_id", "image_id", "volume_id"},
+		nil)
+	libvirtDomainOvirtVCPULimitDesc = newDomainDesc(
+		prometheus.BuildFQName(namespace, "", "ovirt_vcpu_limit_percent"),
+		"Limit of the oVirt CPU QoS profile in percent of the vCPUs of the domain, only for oVirt/RHV VMs with one.",
+		nil,
+		nil)
+)
+
+// ovirtInfoMetrics returns libvirt_domain_ovirt_info,
+// libvirt_domain_ovirt_disk_info and the CPU QoS limit of the domain, if vdsm
+// runs it.
+func (l domainLabels) ovirtInfoMetrics() []prometheus.Metric {
+	vm := l.domain.libvirtSchema.Metadata.OVirtVM
+	if vm.XMLName.Local == "" {
+		return nil
+	}
+	metrics := []prometheus.Metric{
+		l.metric(libvirtDomainOvirtInfoDesc, prometheus.GaugeValue, 1, l.domain.libvirtSchema.UUID, vm.ClusterVersion),
+	}
+	for _, device := range vm.Devices {
+		if device.DevType != "disk" || device.DomainID == "" {
+			continue
+		}
+		metrics = append(metrics, l.metric(libvirtDomainOvirtDiskInfoDesc, prometheus.GaugeValue, 1,
+			device.Name, device.DomainID, device.PoolID, device.ImageID, device.VolumeID))
+	}
+	if limit := l.domain.libvirtSchema.Metadata.OVirtTune.VCPULimit; limit != nil {
+		metrics = append(metrics, l.metric(libvirtDomainOvirtVCPULimitDesc, prometheus.GaugeValue, float64(*limit)))
+	}
+	return metrics
+}
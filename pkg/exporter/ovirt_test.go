@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+const ovirtTestXML = `<domain type="kvm">
+  <name>db-1</name>
+  <uuid>5d0c0a8e-6e4b-4f3a-9a36-2f0d6d0c6f11</uuid>
+  <metadata xmlns:ns0="http://ovirt.org/vm/tune/1.0" xmlns:ovirt-vm="http://ovirt.org/vm/1.0">
+    <ns0:qos>
+      <ns0:vcpuLimit>50</ns0:vcpuLimit>
+    </ns0:qos>
+    <ovirt-vm:vm xmlns:ovirt-vm="http://ovirt.org/vm/1.0">
+      <ovirt-vm:clusterVersion>4.7</ovirt-vm:clusterVersion>
+      <ovirt-vm:device mac_address="56:6f:1a:2b:00:01"/>
+      <ovirt-vm:device devtype="disk" name="sda">
+        <ovirt-vm:domainID>8a1b6bd2-0d5e-4c1a-8e5f-0b1f3f9c2a01</ovirt-vm:domainID>
+        <ovirt-vm:imageID>c3f4a2e1-1111-4d2b-9c3e-5a6b7c8d9e01</ovirt-vm:imageID>
+        <ovirt-vm:poolID>e7a9d1b2-2222-4c3d-8e4f-6a7b8c9d0e01</ovirt-vm:poolID>
+        <ovirt-vm:volumeID>f1e2d3c4-3333-4b5a-9c8d-7e6f5a4b3c01</ovirt-vm:volumeID>
+      </ovirt-vm:device>
+      <ovirt-vm:device devtype="disk" name="hdc"/>
+    </ovirt-vm:vm>
+  </metadata>
+</domain>`
+
+func TestOvirtInfoMetrics(t *testing.T) {
+	schema, err := newLabelSchema(LabelConfig{Domain: []string{"domain"}})
+	require.NoError(t, err)
+
+	var domainSchema libvirt_schema.Domain
+	require.NoError(t, xml.Unmarshal([]byte(ovirtTestXML), &domainSchema))
+	domain := newDomainMeta(libvirt.Domain{Name: "db-1"}, domainSchema)
+
+	metrics := schema.labels(domain).ovirtInfoMetrics()
+	require.Len(t, metrics, 3)
+	assert.Equal(t, map[string]string{
+		"domain":          "db-1",
+		"vm_id":           "5d0c0a8e-6e4b-4f3a-9a36-2f0d6d0c6f11",
+		"cluster_version": "4.7",
+	}, metricLabels(t, metrics[0]))
+	// the CD-ROM without storage is left out
+	assert.Equal(t, map[string]string{
+		"domain":            "db-1",
+		"target_device":     "sda",
+		"storage_domain_id": "8a1b6bd2-0d5e-4c1a-8e5f-0b1f3f9c2a01",
+		"storage_pool_id":   "e7a9d1b2-2222-4c3d-8e4f-6a7b8c9d0e01",
+		"image_id":          "c3f4a2e1-1111-4d2b-9c3e-5a6b7c8d9e01",
+		"volume_id":         "f1e2d3c4-3333-4b5a-9c8d-7e6f5a4b3c01",
+	}, metricLabels(t, metrics[1]))
+
+	var pb dto.Metric
+	require.NoError(t, metrics[2].Write(&pb))
+	assert.Equal(t, 50.0, pb.GetGauge().GetValue())
+
+	// without a CPU QoS profile the qos element is empty
+	domainSchema.Metadata.OVirtTune = libvirt_schema.OVirtTune{}
+	assert.Len(t, schema.labels(newDomainMeta(libvirt.Domain{Name: "db-1"}, domainSchema)).ovirtInfoMetrics(), 2)
+
+	// domains not run by vdsm have none
+	other := newDomainMeta(libvirt.Domain{Name: "web-1"}, libvirt_schema.Domain{})
+	assert.Empty(t, schema.labels(other).ovirtInfoMetrics())
+}
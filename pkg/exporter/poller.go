@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtLastCollectionTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "", "last_collection_timestamp_seconds"),
+		"Unix timestamp of the last background collection from libvirt.",
+		nil,
+		nil)
+	libvirtLastCollectionAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "", "last_collection_age_seconds"),
+		"Seconds since the last background collection from libvirt finished.",
+		nil,
+		nil)
+	libvirtLastCollectionDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt", "", "last_collection_duration_seconds"),
+		"How long the last background collection from libvirt took, in seconds.",
+		nil,
+		nil)
+)
+
+// errNoSnapshot is returned while the first background collection is running.
+var errNoSnapshot = errors.New("no background collection has finished yet")
+
+// snapshot holds the metrics of one background collection.
+type snapshot struct {
+	metrics   []prometheus.Metric
+	err       error
+	timestamp time.Time
+	duration  time.Duration
+}
+
+// poller collects libvirt on a fixed interval and keeps the latest result,
+// so scrapes are answered from memory and every scraper sees the same data.
+type poller struct {
+	exporter *LibvirtExporter
+	interval time.Duration
+
+	mu   sync.RWMutex
+	last *snapshot
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPoller(e *LibvirtExporter, interval time.Duration) *poller {
+	return &poller{
+		exporter: e,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *poller) start() {
+	p.wg.Add(1)
+	go p.run()
+}
+
+// stop waits for a running collection to finish and stops the poll loop.
+func (p *poller) stop() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+func (p *poller) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll()
+		select {
+		case <-ticker.C:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// poll runs one collection, bounded by the poll interval so that a slow
+// libvirt never lets collections pile up.
+func (p *poller) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	start := time.Now()
+	metrics, err := collectToSlice(func(ch chan<- prometheus.Metric) error {
+		return p.exporter.scrape(ctx, ch)
+	})
+	if err != nil {
+		_ = level.Error(p.exporter.logger).Log("err", "background collection failed", "msg", err)
+	}
+
+	p.mu.Lock()
+	p.last = &snapshot{
+		metrics:   metrics,
+		err:       err,
+		timestamp: time.Now(),
+		duration:  time.Since(start),
+	}
+	p.mu.Unlock()
+}
+
+func (p *poller) describe(ch chan<- *prometheus.Desc) {
+	ch <- libvirtLastCollectionTimestampDesc
+	ch <- libvirtLastCollectionAgeDesc
+	ch <- libvirtLastCollectionDurationDesc
+}
+
+// collect sends the latest snapshot and returns the error of the collection
+// that produced it.
+func (p *poller) collect(ch chan<- prometheus.Metric) error {
+	p.mu.RLock()
+	last := p.last
+	p.mu.RUnlock()
+
+	if last == nil {
+		ch <- prometheus.MustNewConstMetric(libvirtUpDesc, prometheus.GaugeValue, 0)
+		return errNoSnapshot
+	}
+
+	for _, m := range last.metrics {
+		ch <- m
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtLastCollectionTimestampDesc, prometheus.GaugeValue, float64(last.timestamp.UnixNano())/1e9)
+	ch <- prometheus.MustNewConstMetric(libvirtLastCollectionAgeDesc, prometheus.GaugeValue, time.Since(last.timestamp).Seconds())
+	ch <- prometheus.MustNewConstMetric(libvirtLastCollectionDurationDesc, prometheus.GaugeValue, last.duration.Seconds())
+	return last.err
+}
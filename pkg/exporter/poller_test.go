@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoller(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "missing-sock")
+	e, err := NewLibvirtExporter(socket, libvirt.QEMUSystem, log.NewNopLogger(), WithPollInterval(time.Hour))
+	require.NoError(t, err)
+	defer e.Close()
+
+	require.Eventually(t, func() bool {
+		e.poller.mu.RLock()
+		defer e.poller.mu.RUnlock()
+		return e.poller.last != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(e)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	names := map[string]bool{}
+	for _, mf := range families {
+		names[mf.GetName()] = true
+	}
+	assert.True(t, names["libvirt_last_collection_timestamp_seconds"])
+	assert.True(t, names["libvirt_last_collection_age_seconds"])
+	assert.True(t, names["libvirt_last_collection_duration_seconds"])
+	assert.True(t, names["libvirt_exporter_connection_up"])
+}
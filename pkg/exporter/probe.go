@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	probeSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("probe", "", "success"),
+		"Whether the probe of the libvirt target was successful.",
+		nil,
+		nil)
+	probeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("probe", "", "duration_seconds"),
+		"How long the probe of the libvirt target took, in seconds.",
+		nil,
+		nil)
+)
+
+// probeCollector collects a single target and reports the outcome as
+// probe_success and probe_duration_seconds.
+type probeCollector struct {
+	ctx      context.Context
+	exporter *LibvirtExporter
+}
+
+func (p probeCollector) Describe(ch chan<- *prometheus.Desc) {
+	p.exporter.Describe(ch)
+	ch <- probeSuccessDesc
+	ch <- probeDurationDesc
+}
+
+func (p probeCollector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+	success := 1.0
+	if err := p.exporter.collect(p.ctx, ch); err != nil {
+		_ = level.Error(p.exporter.logger).Log("err", "probe failed", "msg", err)
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, success)
+	ch <- prometheus.MustNewConstMetric(probeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+}
+
+// ProbeHandler serves /probe?target=<libvirt-uri>&module=<name> in the style
+// of the blackbox exporter. Every request opens its own connection to the
+// target using the settings of the selected module and closes it afterwards.
+// Like the metrics handler, the probe is bounded by the scrape timeout minus
+// timeoutOffset.
+func ProbeHandler(config *Config, timeoutOffset time.Duration, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		target := params.Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		moduleName := params.Get("module")
+		module, ok := config.module(moduleName)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+			return
+		}
+
+		logger := log.With(logger, "target", target, "module", moduleName)
+		e, err := NewLibvirtExporter(target, libvirt.ConnectURI(module.Driver), logger, WithTLSConfig(module.TLS))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer func() {
+			if err := e.Close(); err != nil {
+				_ = level.Warn(logger).Log("warn", "failed to disconnect", "msg", err)
+			}
+		}()
+
+		ctx, cancel := scrapeContext(r, timeoutOffset, 0)
+		defer cancel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(probeCollector{ctx: ctx, exporter: e})
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+modules:
+  default:
+    driver: qemu:///session
+  rack1:
+    tls:
+      cert_file: /etc/exporter/client.pem
+      key_file: /etc/exporter/client-key.pem
+      ca_file: /etc/exporter/ca.pem
+`), 0o600))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	module, ok := cfg.module("")
+	assert.True(t, ok)
+	assert.Equal(t, "qemu:///session", module.Driver)
+
+	module, ok = cfg.module("rack1")
+	assert.True(t, ok)
+	assert.Equal(t, DefaultModule.Driver, module.Driver)
+	assert.Equal(t, "/etc/exporter/client.pem", module.TLS.CertFile)
+
+	_, ok = cfg.module("rack2")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte("modules:\n  x:\n    unknown: 1\n"), 0o600))
+	_, err = LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestProbeHandler(t *testing.T) {
+	handler := ProbeHandler(&Config{}, 0, log.NewNopLogger())
+
+	probe := func(query url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query.Encode(), nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, probe(url.Values{}).Code)
+	assert.Equal(t, http.StatusBadRequest, probe(url.Values{"target": {"/tmp/sock"}, "module": {"missing"}}).Code)
+
+	rec := probe(url.Values{"target": {filepath.Join(t.TempDir(), "missing-sock")}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "probe_success 0")
+	assert.Contains(t, rec.Body.String(), "\nup 0")
+	assert.Contains(t, rec.Body.String(), "probe_duration_seconds")
+}
@@ -1,14 +1,15 @@
 package exporter
 
 import (
+	"context"
 	"encoding/xml"
+	"fmt"
 	"regexp"
 	"time"
-	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/digitalocean/go-libvirt"
-	"github.com/digitalocean/go-libvirt/socket/dialers"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
@@ -29,278 +30,278 @@ var (
 		"Number of domains",
 		nil,
 		nil)
-	libvirtDomainBlockRdTotalTimeSecondsDesc = prometheus.NewDesc(
+	libvirtDomainBlockRdTotalTimeSecondsDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "read_time_seconds_total"),
                 "Total time spent on reads from a block device, in seconds.",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-	libvirtDomainBlockWrTotalTimeSecondsDesc = prometheus.NewDesc(
+	libvirtDomainBlockWrTotalTimeSecondsDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "write_time_seconds_total"),
                 "Total time spent on writes on a block device, in seconds",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-	libvirtDomainMemoryStatDiskCachesBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatDiskCachesBytesDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "memory_stats", "disk_cache_bytes"),
                 "The amount of memory, that can be quickly reclaimed without additional I/O (in bytes)."+
                         "Typically these pages are used for caching files from disk.",
-                []string{"domain", "instance_name", "project_id", "project_name"},
+                nil,
                 nil)
-        libvirtDomainMemoryStatUsedPercentDesc = prometheus.NewDesc(
+        libvirtDomainMemoryStatUsedPercentDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "memory_stats", "used_percent"),
                 "The amount of memory in percent, that used by domain.",
-                []string{"domain", "instance_name", "project_id", "project_name"},
+                nil,
                 nil)
 
 	//domain info
-	libvirtDomainState = prometheus.NewDesc(
+	libvirtDomainState = newDomainDesc(
 		prometheus.BuildFQName(namespace, "info", "state"),
 		"Code of the domain state",
-		[]string{"domain", "instance_name", "project_id", "project_name", "state_desc"},
+		[]string{"state_desc"},
 		nil)
-	libvirtDomainInfoMaxMemDesc = prometheus.NewDesc(
+	libvirtDomainInfoMaxMemDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "info", "maximum_memory_bytes"),
 		"Maximum allowed memory of the domain, in bytes.",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainInfoMemoryDesc = prometheus.NewDesc(
+	libvirtDomainInfoMemoryDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "info", "memory_usage_bytes"),
 		"Memory usage of the domain, in bytes.",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainInfoNrVirtCpuDesc = prometheus.NewDesc(
+	libvirtDomainInfoNrVirtCpuDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "info", "virtual_cpus"),
 		"Number of virtual CPUs for the domain.",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainInfoCpuTimeDesc = prometheus.NewDesc(
+	libvirtDomainInfoCpuTimeDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "info", "cpu_time_seconds_total"),
 		"Amount of CPU time used by the domain, in seconds.",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
 
 	//domain memory stats
-	libvirtDomainMemoryStatsSwapInBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatsSwapInBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "memory_stats", "swap_in_bytes"),
 		"Memory swapped in for this domain(the total amount of data read from swap space)",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainMemoryStatsSwapOutBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatsSwapOutBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "memory_stats", "swap_out_bytes"),
 		"Memory swapped out for this domain (the total amount of memory written out to swap space)",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainMemoryStatsUnusedBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatsUnusedBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "memory_stats", "unused_bytes"),
 		"Memory unused by the domain",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainMemoryStatsAvailableInBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatsAvailableInBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "memory_stats", "available_bytes"),
 		"Memory available to the domain",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainMemoryStatsUsableBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatsUsableBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "memory_stats", "usable_bytes"),
 		"Memory usable by the domain (corresponds to 'Available' in /proc/meminfo)",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainMemoryStatsRssBytesDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatsRssBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "memory_stats", "rss_bytes"),
 		"Resident Set Size of the process running the domain",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-        libvirtDomainMemoryStatFreePercentDesc = prometheus.NewDesc(
+        libvirtDomainMemoryStatFreePercentDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "memory_stats", "free_percent"),
                 "The percentage of memory currently available for use by the instance",
-                []string{"domain", "instance_name", "project_id", "project_name"},
+                nil,
                 nil)
-	libvirtDomainMemoryStatUsednocachePercentDesc = prometheus.NewDesc(
+	libvirtDomainMemoryStatUsednocachePercentDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "memory_stats", "usednocache_percent"),
                 "The percentage of memory currently used without pagecache/buffercache by the instance",
-                []string{"domain", "instance_name", "project_id", "project_name"},
+                nil,
                 nil)
 
 	//domain block stats
-	libvirtDomainBlockStatsInfo = prometheus.NewDesc(
+	libvirtDomainBlockStatsInfo = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "info"),
 		"Metadata information on block devices.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "disk_type", "target_bus", "driver_name", "driver_type", "driver_cache", "driver_discard", "source_file", "source_protocol", "target_device", "serial"},
+		[]string{"disk_type", "target_bus", "driver_name", "driver_type", "driver_cache", "driver_discard", "source_file", "source_protocol", "target_device", "serial"},
 		nil)
-	libvirtDomainBlockStatsRdBytesDesc = prometheus.NewDesc(
+	libvirtDomainBlockStatsRdBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "read_bytes_total"),
 		"Number of bytes read from a block device, in bytes.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+		[]string{"target_device"},
 		nil)
-	libvirtDomainBlockStatsRdReqDesc = prometheus.NewDesc(
+	libvirtDomainBlockStatsRdReqDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "read_requests_total"),
 		"Number of read requests from a block device.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+		[]string{"target_device"},
 		nil)
-	libvirtDomainBlockStatsWrBytesDesc = prometheus.NewDesc(
+	libvirtDomainBlockStatsWrBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "write_bytes_total"),
 		"Number of bytes written from a block device, in bytes.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+		[]string{"target_device"},
 		nil)
-	libvirtDomainBlockStatsWrReqDesc = prometheus.NewDesc(
+	libvirtDomainBlockStatsWrReqDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "write_requests_total"),
 		"Number of write requests from a block device.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+		[]string{"target_device"},
 		nil)
-        libvirtDomainBlockCapacityBytesDesc = prometheus.NewDesc(
+        libvirtDomainBlockCapacityBytesDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "capacity_bytes"),
                 "Logical size in bytes of the block device",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-        libvirtDomainBlockTotalBytesSecDesc = prometheus.NewDesc(
+        libvirtDomainBlockTotalBytesSecDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "limit_total_bytes"),
                 "Total throughput limit in bytes per second",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-        libvirtDomainBlockWriteBytesSecDesc = prometheus.NewDesc(
+        libvirtDomainBlockWriteBytesSecDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "limit_write_bytes"),
                 "Write throughput limit in bytes per second",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-        libvirtDomainBlockReadBytesSecDesc = prometheus.NewDesc(
+        libvirtDomainBlockReadBytesSecDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "limit_read_bytes"),
                 "Read throughput limit in bytes per second",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-        libvirtDomainBlockTotalIopsSecDesc = prometheus.NewDesc(
+        libvirtDomainBlockTotalIopsSecDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "limit_total_requests"),
                 "Total requests per second limit",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-        libvirtDomainBlockWriteIopsSecDesc = prometheus.NewDesc(
+        libvirtDomainBlockWriteIopsSecDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "limit_write_requests"),
                 "Write requests per second limit",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-        libvirtDomainBlockReadIopsSecDesc = prometheus.NewDesc(
+        libvirtDomainBlockReadIopsSecDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "limit_read_requests"),
                 "Read requests per second limit",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device"},
                 nil)
-	libvirtDomainBlockReadBytesPercentDesc = prometheus.NewDesc(
+	libvirtDomainBlockReadBytesPercentDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "read_bytes_usage_percent"),
                 "The percentage of read bytes usage to the read throughput limit.",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device", "window"},
                 nil)
-	libvirtDomainBlockWriteBytesPercentDesc = prometheus.NewDesc(
+	libvirtDomainBlockWriteBytesPercentDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "write_bytes_usage_percent"),
                 "The percentage of write bytes usage to the write throughput limit.",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device", "window"},
                 nil)
-	libvirtDomainBlockTotalBytesPercentDesc = prometheus.NewDesc(
+	libvirtDomainBlockTotalBytesPercentDesc = newDomainDesc(
                 prometheus.BuildFQName(namespace, "block_stats", "total_bytes_usage_percent"),
                 "The percentage of total bytes usage to the total throughput limit.",
-                []string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+                []string{"target_device", "window"},
                 nil)
-	libvirtDomainBlockReadRequestsPercentDesc = prometheus.NewDesc(
+	libvirtDomainBlockReadRequestsPercentDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "read_requests_usage_percent"),
 		"The percentage of read requests usage to the read IOPS limit.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+		[]string{"target_device", "window"},
 		nil)
-	libvirtDomainBlockWriteRequestsPercentDesc = prometheus.NewDesc(
+	libvirtDomainBlockWriteRequestsPercentDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "write_requests_usage_percent"),
 		"The percentage of write requests usage to the IOPS limit.",
-			[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+			[]string{"target_device", "window"},
 		nil)
 
-	libvirtDomainBlockTotalRequestsPercentDesc = prometheus.NewDesc(
+	libvirtDomainBlockTotalRequestsPercentDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "block_stats", "total_requests_usage_percent"),
 		"The percentage of total requests usage to the total IOPS limit.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device"},
+		[]string{"target_device", "window"},
 		nil)
 
 
 	//domain interface stats
-	libvirtDomainInterfaceInfo = prometheus.NewDesc(
+	libvirtDomainInterfaceInfo = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "info"),
 		"Metadata on network interfaces.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "interface_type", "source_bridge", "target_device", "mac_address", "model_type", "mtu_size", "alias_name"},
+		[]string{"interface_type", "source_bridge", "target_device", "mac_address", "model_type", "mtu_size", "alias_name"},
 		nil)
-	libvirtDomainInterfaceRxBytesDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceRxBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "receive_bytes_total"),
 		"Number of bytes received on a network interface, in bytes.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceRxPacketsDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceRxPacketsDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "receive_packets_total"),
 		"Number of packets received on a network interface.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceRxErrsDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceRxErrsDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "receive_errors_total"),
 		"Number of packet receive errors on a network interface.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceRxDropDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceRxDropDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "receive_drops_total"),
 		"Number of packet receive drops on a network interface.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceTxBytesDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceTxBytesDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "transmit_bytes_total"),
 		"Number of bytes transmitted on a network interface, in bytes.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceTxPacketsDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceTxPacketsDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "transmit_packets_total"),
 		"Number of packets transmitted on a network interface.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceTxErrsDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceTxErrsDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "transmit_errors_total"),
 		"Number of packet transmit errors on a network interface.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
-	libvirtDomainInterfaceTxDropDesc = prometheus.NewDesc(
+	libvirtDomainInterfaceTxDropDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "interface_stats", "transmit_drops_total"),
 		"Number of packet transmit drops on a network interface.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "target_device", "alias_name"},
+		[]string{"target_device", "alias_name"},
 		nil)
 
 	// domain vcpu stats
-	libvirtDomainVCPUStatsCurrent = prometheus.NewDesc(
+	libvirtDomainVCPUStatsCurrent = newDomainDesc(
 		prometheus.BuildFQName(namespace, "vcpu", "current"),
 		"Number of current online vCPUs.",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainVCPUStatsMaximum = prometheus.NewDesc(
+	libvirtDomainVCPUStatsMaximum = newDomainDesc(
 		prometheus.BuildFQName(namespace, "vcpu", "maximum"),
 		"Number of maximum online vCPUs.",
-		[]string{"domain", "instance_name", "project_id", "project_name"},
+		nil,
 		nil)
-	libvirtDomainVCPUStatsState = prometheus.NewDesc(
+	libvirtDomainVCPUStatsState = newDomainDesc(
 		prometheus.BuildFQName(namespace, "vcpu", "state"),
 		"State of the vCPU.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "vcpu"},
+		[]string{"vcpu"},
 		nil)
-	libvirtDomainVCPUStatsTime = prometheus.NewDesc(
+	libvirtDomainVCPUStatsTime = newDomainDesc(
 		prometheus.BuildFQName(namespace, "vcpu", "time_seconds_total"),
 		"Time spent by the virtual CPU.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "vcpu"},
+		[]string{"vcpu"},
 		nil)
-	libvirtDomainVCPUStatsWait = prometheus.NewDesc(
+	libvirtDomainVCPUStatsWait = newDomainDesc(
 		prometheus.BuildFQName(namespace, "vcpu", "wait_seconds_total"),
 		"Time the vCPU wants to run, but the host scheduler has something else running ahead of it.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "vcpu"},
+		[]string{"vcpu"},
 		nil)
-	libvirtDomainVCPUStatsDelay = prometheus.NewDesc(
+	libvirtDomainVCPUStatsDelay = newDomainDesc(
 		prometheus.BuildFQName(namespace, "vcpu", "delay_seconds_total"),
 		"Time the vCPU spent waiting in the queue instead of running. Exposed to the VM as steal time.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "vcpu"},
+		[]string{"vcpu"},
 		nil)
-	libvirtDomainVCPUStatsSysPercent = prometheus.NewDesc(
+	libvirtDomainVCPUStatsSysPercent = newDomainDesc(
                 prometheus.BuildFQName(namespace, "vcpu", "sys_percent"),
                 "CPU usage percent by instance on all vcpus",
-                []string{"domain", "instance_name", "project_id", "project_name", "vcpu"},
+                []string{"vcpu", "window"},
                 nil)
-	libvirtDomainVCPUStatsStealPercent = prometheus.NewDesc(
+	libvirtDomainVCPUStatsStealPercent = newDomainDesc(
                 prometheus.BuildFQName(namespace, "vcpu", "steal_percent"),
                 "The percentage of time the virtual machine process is waiting on the physical CPU for its CPU time",
-                []string{"domain", "instance_name", "project_id", "project_name", "vcpu"},
+                []string{"vcpu", "window"},
                 nil)
 
 
@@ -326,18 +327,19 @@ var (
 		[]string{"storage_pool"},
 		nil)
 
-	// info metrics
-	libvirtDomainInfoDesc = prometheus.NewDesc(
+	// info metrics, their labels besides the domain labels are chosen by the
+	// labelSchema
+	libvirtDomainInfoDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "domain",  "info"),
 		"Metadata labels for the domain.",
-		[]string{"domain", "instance_name", "project_id", "project_name", "os_type", "os_type_arch", "os_type_machine"},
+		nil,
 		nil)
 
 	// info metrics from metadata extracted OpenStack Nova
-	libvirtDomainOpenstackInfoDesc = prometheus.NewDesc(
+	libvirtDomainOpenstackInfoDesc = newDomainDesc(
 		prometheus.BuildFQName(namespace, "", "openstack_info"),
 		"OpenStack Metadata labels for the domain.",
-		[]string{"domain",  "instance_name", "instance_id", "flavor_name", "user_name", "user_id", "project_name", "project_id"},
+		nil,
 		nil)
 
 	domainState = map[libvirt_schema.DomainState]string{
@@ -353,7 +355,112 @@ var (
 	}
 )
 
-type collectFunc func(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels []string, logger log.Logger) (err error)
+// baseDomainDescs are the per-domain metrics exported besides the collectors.
+var baseDomainDescs = append([]*domainDesc{
+	libvirtDomainInfoDesc,
+	libvirtDomainOpenstackInfoDesc,
+	libvirtDomainOvirtInfoDesc,
+	libvirtDomainOvirtDiskInfoDesc,
+	libvirtDomainOvirtVCPULimitDesc,
+	//domain info
+	libvirtDomainState,
+	libvirtDomainInfoMaxMemDesc,
+	libvirtDomainInfoMemoryDesc,
+	libvirtDomainInfoNrVirtCpuDesc,
+	libvirtDomainInfoCpuTimeDesc,
+}, openstackDescs...)
+
+type collectFunc func(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, logger log.Logger) (err error)
+
+func init() {
+	registerCollector(&collector{
+		name: "block",
+		domainDescs: []*domainDesc{
+			libvirtDomainBlockStatsInfo,
+			libvirtDomainBlockStatsRdBytesDesc,
+			libvirtDomainBlockStatsRdReqDesc,
+			libvirtDomainBlockStatsWrBytesDesc,
+			libvirtDomainBlockStatsWrReqDesc,
+			libvirtDomainBlockCapacityBytesDesc,
+			libvirtDomainBlockRdTotalTimeSecondsDesc,
+			libvirtDomainBlockWrTotalTimeSecondsDesc,
+		},
+		domain: CollectDomainBlockDeviceInfo,
+	}, true)
+	registerCollector(&collector{
+		name: "blockiotune",
+		domainDescs: []*domainDesc{
+			libvirtDomainBlockTotalBytesSecDesc,
+			libvirtDomainBlockReadBytesSecDesc,
+			libvirtDomainBlockWriteBytesSecDesc,
+			libvirtDomainBlockTotalIopsSecDesc,
+			libvirtDomainBlockReadIopsSecDesc,
+			libvirtDomainBlockWriteIopsSecDesc,
+			libvirtDomainBlockReadBytesPercentDesc,
+			libvirtDomainBlockWriteBytesPercentDesc,
+			libvirtDomainBlockTotalBytesPercentDesc,
+			libvirtDomainBlockReadRequestsPercentDesc,
+			libvirtDomainBlockWriteRequestsPercentDesc,
+			libvirtDomainBlockTotalRequestsPercentDesc,
+		},
+		domain: CollectDomainBlockIOTuneInfo,
+	}, true)
+	registerCollector(&collector{
+		name: "interface",
+		domainDescs: []*domainDesc{
+			libvirtDomainInterfaceInfo,
+			libvirtDomainInterfaceRxBytesDesc,
+			libvirtDomainInterfaceRxPacketsDesc,
+			libvirtDomainInterfaceRxErrsDesc,
+			libvirtDomainInterfaceRxDropDesc,
+			libvirtDomainInterfaceTxBytesDesc,
+			libvirtDomainInterfaceTxPacketsDesc,
+			libvirtDomainInterfaceTxErrsDesc,
+			libvirtDomainInterfaceTxDropDesc,
+		},
+		domain: CollectDomainNetworkInfo,
+	}, true)
+	registerCollector(&collector{
+		name: "memory",
+		domainDescs: []*domainDesc{
+			libvirtDomainMemoryStatsSwapInBytesDesc,
+			libvirtDomainMemoryStatsSwapOutBytesDesc,
+			libvirtDomainMemoryStatsUnusedBytesDesc,
+			libvirtDomainMemoryStatsAvailableInBytesDesc,
+			libvirtDomainMemoryStatsUsableBytesDesc,
+			libvirtDomainMemoryStatsRssBytesDesc,
+			libvirtDomainMemoryStatDiskCachesBytesDesc,
+			libvirtDomainMemoryStatUsedPercentDesc,
+			libvirtDomainMemoryStatFreePercentDesc,
+			libvirtDomainMemoryStatUsednocachePercentDesc,
+		},
+		domain: CollectDomainMemoryStatInfo,
+	}, true)
+	registerCollector(&collector{
+		name: "vcpu",
+		domainDescs: []*domainDesc{
+			libvirtDomainVCPUStatsCurrent,
+			libvirtDomainVCPUStatsMaximum,
+			libvirtDomainVCPUStatsState,
+			libvirtDomainVCPUStatsTime,
+			libvirtDomainVCPUStatsWait,
+			libvirtDomainVCPUStatsDelay,
+			libvirtDomainVCPUStatsSysPercent,
+			libvirtDomainVCPUStatsStealPercent,
+		},
+		domain: CollectDomainVCPUInfo,
+	}, true)
+	registerCollector(&collector{
+		name: "storagepool",
+		descs: []*prometheus.Desc{
+			libvirtStoragePoolState,
+			libvirtStoragePoolCapacity,
+			libvirtStoragePoolAllocation,
+			libvirtStoragePoolAvailable,
+		},
+		host: CollectStoragePools,
+	}, true)
+}
 
 type domainMeta struct {
 	domainName      string
@@ -370,8 +477,28 @@ type domainMeta struct {
 	projectName string
 	projectId   string
 
+	imageId        string
+	packageVersion string
+
+	// namespace, name and UID of the KubeVirt VMI
+	kubevirtNamespace string
+	kubevirtVMI       string
+	kubevirtUID       string
+
 	libvirtDomain libvirt.Domain
 	libvirtSchema libvirt_schema.Domain
+
+	// metadata holds the values of the metadata labels of the labelSchema.
+	metadata map[string]string
+
+	// maxMemory is the maximum memory of the domain in KiB, filled in by CollectDomain.
+	maxMemory uint64
+
+	// stats is the bulk ConnectGetAllDomainStats record of the domain, nil
+	// when the stats are fetched with per-domain calls.
+	stats *domainStats
+	// rates tracks the counters the *_percent metrics are derived from.
+	rates *rateTracker
 }
 
 // LibvirtExporter implements a Prometheus exporter for libvirt state.
@@ -379,94 +506,429 @@ type LibvirtExporter struct {
 	uri    string
 	driver libvirt.ConnectURI
 
+	keepaliveInterval   time.Duration
+	reconnectBackoffMin time.Duration
+	reconnectBackoffMax time.Duration
+	tlsConfig           TLSConfig
+	bulkStats           bool
+	workers             int
+	scrapeTimeout       time.Duration
+	pollInterval        time.Duration
+	lifecycleEvents     bool
+	domainCacheTTL      time.Duration
+	sampleInterval      time.Duration
+	rateWindows         []time.Duration
+	filter              *FilterConfig
+	labelConfig         LabelConfig
+	collectorNames      []string
+	collectors          collectorSet
+	labels              *labelSchema
+
+	conn    *libvirtConnection
+	poller  *poller
+	events  *lifecycleEvents
+	sampler *sampler
+
+	domainCache *domainCache
+	rates       *rateTracker
+
+	domainsTimedOut atomic.Uint64
+	domainErrors    atomic.Uint64
+	errors          errorCounter
+
 	logger log.Logger
 }
 
+// Option configures optional LibvirtExporter settings.
+type Option func(*LibvirtExporter)
+
+// WithKeepaliveInterval sets how often the libvirt connection is checked for liveness.
+func WithKeepaliveInterval(interval time.Duration) Option {
+	return func(e *LibvirtExporter) {
+		e.keepaliveInterval = interval
+	}
+}
+
+// WithReconnectBackoff sets the bounds of the exponential backoff between reconnect attempts.
+func WithReconnectBackoff(min, max time.Duration) Option {
+	return func(e *LibvirtExporter) {
+		e.reconnectBackoffMin = min
+		e.reconnectBackoffMax = max
+	}
+}
+
+// WithTLSConfig sets the client certificate used for qemu+tls:// URIs.
+func WithTLSConfig(config TLSConfig) Option {
+	return func(e *LibvirtExporter) {
+		e.tlsConfig = config
+	}
+}
+
+// WithBulkStats enables fetching the stats of all domains with a single
+// ConnectGetAllDomainStats call instead of several calls per domain and device.
+func WithBulkStats(enabled bool) Option {
+	return func(e *LibvirtExporter) {
+		e.bulkStats = enabled
+	}
+}
+
+// WithWorkers sets the number of domains that are collected concurrently.
+func WithWorkers(workers int) Option {
+	return func(e *LibvirtExporter) {
+		e.workers = workers
+	}
+}
+
+// WithScrapeTimeout sets the collection deadline used when a scrape does not
+// carry the X-Prometheus-Scrape-Timeout-Seconds header. Zero disables it.
+func WithScrapeTimeout(timeout time.Duration) Option {
+	return func(e *LibvirtExporter) {
+		e.scrapeTimeout = timeout
+	}
+}
+
+// WithPollInterval makes the exporter collect libvirt in the background
+// every interval and serve scrapes from the latest result. Zero, the default,
+// collects libvirt on every scrape.
+func WithPollInterval(interval time.Duration) Option {
+	return func(e *LibvirtExporter) {
+		e.pollInterval = interval
+	}
+}
+
+// WithLifecycleEvents subscribes the exporter to domain lifecycle events and
+// exports them as counters.
+func WithLifecycleEvents(enabled bool) Option {
+	return func(e *LibvirtExporter) {
+		e.lifecycleEvents = enabled
+	}
+}
+
+// WithCollectors sets the collectors the exporter runs, overriding the
+// --collector.<name> flags.
+func WithCollectors(names ...string) Option {
+	return func(e *LibvirtExporter) {
+		e.collectorNames = names
+	}
+}
+
+// WithDomainCacheTTL caches the parsed XML of every domain for at most ttl.
+// Entries are also invalidated by domain events. Zero, the default, fetches
+// the XML of every domain on every scrape.
+func WithDomainCacheTTL(ttl time.Duration) Option {
+	return func(e *LibvirtExporter) {
+		e.domainCacheTTL = ttl
+	}
+}
+
+// WithRateWindows samples the counters the *_percent metrics are derived
+// from every interval in the background and exports the utilization over
+// each of windows. A zero interval, the default, disables the percent metrics.
+func WithRateWindows(interval time.Duration, windows ...time.Duration) Option {
+	return func(e *LibvirtExporter) {
+		e.sampleInterval = interval
+		e.rateWindows = windows
+	}
+}
+
+// WithDomainFilter collects only the domains selected by filter.
+func WithDomainFilter(filter FilterConfig) Option {
+	return func(e *LibvirtExporter) {
+		e.filter = &filter
+	}
+}
+
+// WithLabels sets the labels that identify a domain on its series.
+func WithLabels(config LabelConfig) Option {
+	return func(e *LibvirtExporter) {
+		e.labelConfig = config
+	}
+}
+
 // NewLibvirtExporter creates a new Prometheus exporter for libvirt.
-func NewLibvirtExporter(uri string, driver libvirt.ConnectURI, logger log.Logger) (*LibvirtExporter, error) {
-	return &LibvirtExporter{
-		uri:    uri,
-		driver: driver,
-		logger: logger,
-	}, nil
+// uri is either the path of a local libvirt socket, which is opened with
+// driver, or a full libvirt URI like qemu+tls://host/system.
+// The exporter keeps a persistent connection to libvirt until Close is called.
+func NewLibvirtExporter(uri string, driver libvirt.ConnectURI, logger log.Logger, opts ...Option) (*LibvirtExporter, error) {
+	e := &LibvirtExporter{
+		uri:                 uri,
+		driver:              driver,
+		keepaliveInterval:   defaultKeepaliveInterval,
+		reconnectBackoffMin: defaultReconnectBackoffMin,
+		reconnectBackoffMax: defaultReconnectBackoffMax,
+		bulkStats:           true,
+		workers:             defaultWorkers,
+		collectorNames:      enabledCollectors(),
+		labelConfig:         DefaultLabelConfig,
+		logger:              logger,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.workers < 1 {
+		return nil, fmt.Errorf("number of workers must be at least 1, got %d", e.workers)
+	}
+	if e.domainCacheTTL < 0 {
+		return nil, fmt.Errorf("domain cache TTL must not be negative, got %s", e.domainCacheTTL)
+	}
+	if e.pollInterval < 0 {
+		return nil, fmt.Errorf("poll interval must not be negative, got %s", e.pollInterval)
+	}
+	if e.sampleInterval < 0 {
+		return nil, fmt.Errorf("sample interval must not be negative, got %s", e.sampleInterval)
+	}
+	if e.sampleInterval > 0 && len(e.rateWindows) == 0 {
+		return nil, fmt.Errorf("at least one rate window is required to sample every %s", e.sampleInterval)
+	}
+	for _, window := range e.rateWindows {
+		if window < e.sampleInterval {
+			return nil, fmt.Errorf("rate window %s is shorter than the sample interval %s", window, e.sampleInterval)
+		}
+	}
+	if e.filter != nil {
+		if err := e.filter.validate(); err != nil {
+			return nil, fmt.Errorf("invalid domain filter: %w", err)
+		}
+	}
+	if e.keepaliveInterval <= 0 {
+		return nil, fmt.Errorf("keepalive interval must be positive, got %s", e.keepaliveInterval)
+	}
+	if e.reconnectBackoffMin <= 0 || e.reconnectBackoffMax < e.reconnectBackoffMin {
+		return nil, fmt.Errorf("invalid reconnect backoff range %s-%s", e.reconnectBackoffMin, e.reconnectBackoffMax)
+	}
+
+	labels, err := newLabelSchema(e.labelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labels: %w", err)
+	}
+	e.labels = labels
+
+	collectors, err := newCollectorSet(e.collectorNames)
+	if err != nil {
+		return nil, err
+	}
+	e.collectors = collectors
+	_ = level.Debug(logger).Log("msg", "enabled collectors", "collectors", strings.Join(e.collectorNames, ","))
+
+	target, err := parseLibvirtURI(uri, driver, e.tlsConfig, defaultDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	e.conn = newLibvirtConnection(target, e.keepaliveInterval, e.reconnectBackoffMin, e.reconnectBackoffMax, logger)
+	e.conn.start()
+	if e.domainCacheTTL > 0 {
+		e.domainCache = newDomainCache(e.domainCacheTTL)
+	}
+	// the cache relies on events for invalidation, even if they are not exported
+	if e.lifecycleEvents || e.domainCache != nil {
+		e.events = newLifecycleEvents(e.conn, e.keepaliveInterval, e.lifecycleEvents, e.domainCache, e.filter, e.labels, logger)
+		e.events.start()
+	}
+	if e.sampleInterval > 0 {
+		e.rates = newRateTracker(e.sampleInterval, e.rateWindows)
+		e.sampler = newSampler(e.conn, e.rates, logger)
+		e.sampler.start()
+	}
+	if e.pollInterval > 0 {
+		e.poller = newPoller(e, e.pollInterval)
+		e.poller.start()
+	}
+
+	return e, nil
+}
+
+// Close stops the background collection, sampling, event and keepalive loops
+// and closes the libvirt connection.
+func (e *LibvirtExporter) Close() error {
+	if e.poller != nil {
+		e.poller.stop()
+	}
+	if e.sampler != nil {
+		e.sampler.stop()
+	}
+	if e.events != nil {
+		e.events.stop()
+	}
+	return e.conn.close()
 }
 
 // DomainFromLibvirt retrives all domains from the libvirt socket and enriches them with some meta information.
 func DomainsFromLibvirt(l *libvirt.Libvirt, logger log.Logger) ([]domainMeta, error) {
+	domains, _, err := domainsFromLibvirt(l, nil, nil, nil, logger)
+	return domains, err
+}
+
+// domainsFromLibvirt is DomainsFromLibvirt taking the parsed XML from cache
+// where possible, extracting the metadata labels of labels and leaving out
+// the domains not selected by filter. cache, filter and labels may be nil.
+func domainsFromLibvirt(l *libvirt.Libvirt, cache *domainCache, filter *FilterConfig, labels *labelSchema, logger log.Logger) ([]domainMeta, filterCounts, error) {
 	domains, _, err := l.ConnectListAllDomains(1, 0)
 	if err != nil {
 		_ = level.Error(logger).Log("err", "failed to load domains", "msg", err)
-		return nil, err
+		return nil, filterCounts{}, err
 	}
+	if cache != nil {
+		cache.retain(domains)
+	}
+
+	now := time.Now()
+	lvDomains := make([]domainMeta, 0, len(domains))
+	for _, domain := range domains {
+		if cache != nil {
+			if meta, ok := cache.get(domain, now); ok {
+				lvDomains = append(lvDomains, meta)
+				continue
+			}
+		}
 
-	lvDomains := make([]domainMeta, len(domains))
-	for idx, domain := range domains {
 		xmlDesc, err := l.DomainGetXMLDesc(domain, 0)
-		if err != nil {
-			_ = level.Error(logger).Log("err", "failed to DomainGetXMLDesc", "domain", "instance_name", "project_id", "project_name", domain.Name, "msg", err)
+		if libvirt.IsNotFound(err) {
+			// undefined since it was listed
+			continue
+		} else if err != nil {
+			_ = level.Error(logger).Log("err", "failed to DomainGetXMLDesc", "domain", domain.Name, "msg", err)
 			continue
 		}
 		var libvirtSchema libvirt_schema.Domain
 		if err = xml.Unmarshal([]byte(xmlDesc), &libvirtSchema); err != nil {
-			_ = level.Error(logger).Log("err", "failed to unmarshal domain", "domain", "instance_name", "project_id", "project_name", domain.Name, "msg", err)
+			_ = level.Error(logger).Log("err", "failed to unmarshal domain", "domain", domain.Name, "msg", err)
+			continue
+		}
+
+		meta := newDomainMeta(domain, libvirtSchema)
+
+		if meta.metadata, err = labels.extractMetadata(xmlDesc); err != nil {
+			_ = level.Error(logger).Log("err", "failed to extract metadata labels", "domain", domain.Name, "msg", err)
 			continue
 		}
 
-		lvDomains[idx].libvirtDomain = domain
-		lvDomains[idx].libvirtSchema = libvirtSchema
+		if cache != nil {
+			cache.put(meta, now)
+		}
+		lvDomains = append(lvDomains, meta)
+	}
+
+	lvDomains, filtered := filter.apply(l, lvDomains, logger)
+	return lvDomains, filtered, nil
+}
+
+// newDomainMeta returns the metadata of domain read from its XML description.
+func newDomainMeta(domain libvirt.Domain, libvirtSchema libvirt_schema.Domain) domainMeta {
+	var meta domainMeta
+	meta.libvirtDomain = domain
+	meta.libvirtSchema = libvirtSchema
 
-		lvDomains[idx].domainName = domain.Name
-		lvDomains[idx].instanceName = libvirtSchema.Metadata.NovaInstance.Name
-		lvDomains[idx].instanceId = libvirtSchema.UUID
-		lvDomains[idx].flavorName = libvirtSchema.Metadata.NovaInstance.Flavor.FlavorName
-		lvDomains[idx].os_type_arch = libvirtSchema.OSMetadata.Type.Arch
-		lvDomains[idx].os_type_machine = libvirtSchema.OSMetadata.Type.Machine
-		lvDomains[idx].os_type = libvirtSchema.OSMetadata.Type.Value
+	meta.domainName = domain.Name
+	meta.instanceName = libvirtSchema.Metadata.NovaInstance.Name
+	meta.instanceId = libvirtSchema.UUID
+	meta.flavorName = libvirtSchema.Metadata.NovaInstance.Flavor.FlavorName
+	meta.os_type_arch = libvirtSchema.OSMetadata.Type.Arch
+	meta.os_type_machine = libvirtSchema.OSMetadata.Type.Machine
+	meta.os_type = libvirtSchema.OSMetadata.Type.Value
 
-		lvDomains[idx].userName = libvirtSchema.Metadata.NovaInstance.Owner.User.UserName
-		lvDomains[idx].userId = libvirtSchema.Metadata.NovaInstance.Owner.User.UserId
+	meta.userName = libvirtSchema.Metadata.NovaInstance.Owner.User.UserName
+	meta.userId = libvirtSchema.Metadata.NovaInstance.Owner.User.UserId
 
-		lvDomains[idx].projectName = libvirtSchema.Metadata.NovaInstance.Owner.Project.ProjectName
-		lvDomains[idx].projectId = libvirtSchema.Metadata.NovaInstance.Owner.Project.ProjectId
+	meta.projectName = libvirtSchema.Metadata.NovaInstance.Owner.Project.ProjectName
+	meta.projectId = libvirtSchema.Metadata.NovaInstance.Owner.Project.ProjectId
+
+	if root := libvirtSchema.Metadata.NovaInstance.Root; root.Type == "image" {
+		meta.imageId = root.UUID
 	}
+	meta.packageVersion = libvirtSchema.Metadata.NovaInstance.Package.Version
 
-	return lvDomains, nil
+	if kubevirt := libvirtSchema.Metadata.KubeVirt; kubevirt.UID != "" {
+		meta.kubevirtUID = kubevirt.UID
+		// Kubernetes namespaces cannot contain underscores
+		meta.kubevirtNamespace, meta.kubevirtVMI, _ = strings.Cut(domain.Name, "_")
+	}
+	return meta
 }
 
 // Collect scrapes Prometheus metrics from libvirt.
 func (e *LibvirtExporter) Collect(ch chan<- prometheus.Metric) {
-	if err := CollectFromLibvirt(ch, e.uri, e.driver, e.logger); err != nil {
-		_ = level.Error(e.logger).Log("err", "failed to collect metrics", "msg", err)
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if e.scrapeTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.scrapeTimeout)
 	}
+	defer cancel()
+	e.collectLogged(ctx, ch)
 }
 
-
-// CollectFromLibvirt obtains Prometheus metrics from all domains in a libvirt setup.
-func CollectFromLibvirt(ch chan<- prometheus.Metric, uri string, driver libvirt.ConnectURI, logger log.Logger) (err error) {
-	dialer := dialers.NewLocal(dialers.WithSocket(uri), dialers.WithLocalTimeout((5 * time.Second)))
-	l := libvirt.NewWithDialer(dialer)
-	if err = l.ConnectToURI(driver); err != nil {
-		_ = level.Error(logger).Log("err", "failed to connect", "msg", err)
-		return err
+func (e *LibvirtExporter) collectLogged(ctx context.Context, ch chan<- prometheus.Metric) {
+	if err := e.collect(ctx, ch); err != nil {
+		_ = level.Error(e.logger).Log("err", "failed to collect metrics", "msg", err)
 	}
+}
 
+// collect is Collect returning the scrape error. In polling mode it serves
+// the latest background collection instead of querying libvirt.
+func (e *LibvirtExporter) collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	defer e.conn.collect(ch)
+	if e.lifecycleEvents {
+		defer e.events.collect(ch)
+	}
+	if e.domainCache != nil {
+		defer e.domainCache.collect(ch)
+	}
 	defer func() {
-		if err := l.Disconnect(); err != nil {
-			_ = level.Error(logger).Log("err", "failed to disconnect", "msg", err)
-		}
+		ch <- prometheus.MustNewConstMetric(libvirtDomainsTimedOutDesc, prometheus.CounterValue, float64(e.domainsTimedOut.Load()))
+		ch <- prometheus.MustNewConstMetric(libvirtDomainErrorsDesc, prometheus.CounterValue, float64(e.domainErrors.Load()))
+		e.errors.collect(ch)
 	}()
 
+	if e.poller != nil {
+		return e.poller.collect(ch)
+	}
+	return e.scrape(ctx, ch)
+}
+
+// scrape collects all metrics from libvirt and reports up 0 if libvirt
+// cannot be reached.
+func (e *LibvirtExporter) scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
+	l, err := e.conn.get()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(libvirtUpDesc, prometheus.GaugeValue, 0)
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	return e.CollectFromLibvirt(ctx, ch, l)
+}
+
+
+// CollectFromLibvirt obtains Prometheus metrics from all domains in a libvirt setup.
+// Domains are collected concurrently and abandoned once ctx expires. A domain
+// that fails is left out and counted, the other domains and the host
+// collectors are still collected.
+func (e *LibvirtExporter) CollectFromLibvirt(ctx context.Context, ch chan<- prometheus.Metric, l *libvirt.Libvirt) (err error) {
+	logger := e.logger
+
+	domains, filtered, err := domainsFromLibvirt(l, e.domainCache, e.filter, e.labels, logger)
+	if err != nil {
+		_ = level.Error(logger).Log("err", "failed to retrieve domains from Libvirt", "msg", err)
+		e.errors.add(err)
+		ch <- prometheus.MustNewConstMetric(
+			libvirtUpDesc,
+			prometheus.GaugeValue,
+			0)
+		return err
+	}
 	ch <- prometheus.MustNewConstMetric(
 		libvirtUpDesc,
 		prometheus.GaugeValue,
 		1.0)
+	filtered.collect(ch)
+	if e.events != nil {
+		e.events.observe(domains)
+	}
 
-	domains, err := DomainsFromLibvirt(l, logger)
-	if err != nil {
-		_ = level.Error(logger).Log("err", "failed to retrieve domains from Libvirt", "msg", err)
-		return err
+	for idx := range domains {
+		domains[idx].rates = e.rates
 	}
 
+	stats := newCollectorStats(e.collectors, &e.errors)
+	defer stats.collect(ch)
+	collectors := stats.instrument(e.collectors)
+
 
 	domainNumber := len(domains)
 	ch <- prometheus.MustNewConstMetric(
@@ -474,112 +936,98 @@ func CollectFromLibvirt(ch chan<- prometheus.Metric, uri string, driver libvirt.
 		prometheus.GaugeValue,
 		float64(domainNumber))
 
-	// collect domain metrics from libvirt
-	// see https://libvirt.org/html/libvirt-libvirt-domain.html
-	for _, domain := range domains {
-		if err = CollectDomain(ch, l, domain, logger); err != nil {
-			_ = level.Error(logger).Log("err", "failed to collect domain", "domain", "instance_name", "project_id", "project_name", domain.domainName, "msg", err)
-			return err
+	// fetch the stats of all domains at once, falling back to per-domain
+	// calls on libvirt versions without ConnectGetAllDomainStats
+	if e.bulkStats && len(domains) > 0 {
+		if err = fetchBulkStats(l, domains); err != nil {
+			_ = level.Warn(logger).Log("warn", "failed to get bulk domain stats, falling back to per-domain calls", "msg", err)
 		}
 	}
 
-	// collect storage pool metrics
-	// see https://libvirt.org/html/libvirt-libvirt-storage.html
-	var pools []libvirt.StoragePool
-	if pools, _, err = l.ConnectListAllStoragePools(1, 0); err != nil {
-		_ = level.Error(logger).Log("err", "failed to collect storage pools", "msg", err)
-		return err
-	}
-	for _, pool := range pools {
-		if err = CollectStoragePoolInfo(ch, l, pool, logger); err != nil {
-			_ = level.Error(logger).Log("err", "failed to collect storage pool info", "msg", err)
-			return err
+	// collect domain metrics from libvirt
+	// see https://libvirt.org/html/libvirt-libvirt-domain.html
+	// the host collectors still run once the scrape deadline is exceeded
+	err = e.collectDomains(ctx, ch, l, domains, collectors.domainCollectors())
+
+	// collect host-wide metrics such as storage pools
+	for _, c := range collectors.hostCollectors() {
+		if hostErr := c.host(ch, l, logger); hostErr != nil {
+			_ = level.Error(logger).Log("err", "failed to collect host metrics", "collector", c.name, "msg", hostErr)
+			if err == nil {
+				err = hostErr
+			}
 		}
 	}
 
-	return nil
+	return err
 }
 
-var rmaxmem, rmemory uint64
-
-// CollectDomain extracts Prometheus metrics from a libvirt domain.
-func CollectDomain(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, logger log.Logger) (err error) {
+// CollectDomain extracts Prometheus metrics from a libvirt domain, labelled
+// as chosen by labels. The domain collectors are only run for active domains.
+func CollectDomain(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, labels *labelSchema, collectors []*collector, logger log.Logger) (err error) {
 
 	var rState uint8
+	var rmaxmem, rmemory uint64
 	var rvirCpu uint16
 	var rcputime uint64
-	if rState, rmaxmem, rmemory, rvirCpu, rcputime, err = l.DomainGetInfo(domain.libvirtDomain); err != nil {
-		_ = level.Error(logger).Log("err", "failed to get domainInfo", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+	if domain.stats != nil {
+		rState, rmaxmem, rmemory, rvirCpu, rcputime = domain.stats.domainInfo()
+	} else if rState, rmaxmem, rmemory, rvirCpu, rcputime, err = l.DomainGetInfo(domain.libvirtDomain); libvirt.IsNotFound(err) {
+		return err
+	} else if err != nil {
+		_ = level.Error(logger).Log("err", "failed to get domainInfo", "domain", domain.libvirtDomain.Name, "msg", err)
 		return err
 	}
 
-	promLabels := []string{
-		domain.domainName,
-		domain.instanceName,
-		domain.projectName,
-                domain.projectId,
+	promLabels := labels.labels(domain)
+	for _, m := range promLabels.infoMetrics() {
+		ch <- m
 	}
-
-	openstackInfoLabels := []string{
-		domain.domainName,
-		domain.instanceName,
-		domain.instanceId,
-		domain.flavorName,
-		domain.userName,
-		domain.userId,
-		domain.projectName,
-		domain.projectId,
+	for _, m := range promLabels.ovirtInfoMetrics() {
+		ch <- m
 	}
-
-	infoLabels := []string{
-		domain.domainName,
-		domain.instanceName,
-                domain.projectName,
-                domain.projectId,
-		domain.os_type,
-		domain.os_type_arch,
-		domain.os_type_machine,
+	for _, m := range promLabels.openstackMetrics() {
+		ch <- m
 	}
 
-	ch <- prometheus.MustNewConstMetric(libvirtDomainInfoDesc, prometheus.GaugeValue, 1.0, infoLabels...)
-	ch <- prometheus.MustNewConstMetric(libvirtDomainOpenstackInfoDesc, prometheus.GaugeValue, 1.0, openstackInfoLabels...)
-
-	ch <- prometheus.MustNewConstMetric(libvirtDomainState, prometheus.GaugeValue, float64(rState), append(promLabels, domainState[libvirt_schema.DomainState(rState)])...)
+	ch <- promLabels.metric(libvirtDomainState, prometheus.GaugeValue, float64(rState), domainState[libvirt_schema.DomainState(rState)])
 
-	ch <- prometheus.MustNewConstMetric(libvirtDomainInfoMaxMemDesc, prometheus.GaugeValue, float64(rmaxmem)*1024, promLabels...)
-	ch <- prometheus.MustNewConstMetric(libvirtDomainInfoMemoryDesc, prometheus.GaugeValue, float64(rmemory)*1024, promLabels...)
-	ch <- prometheus.MustNewConstMetric(libvirtDomainInfoNrVirtCpuDesc, prometheus.GaugeValue, float64(rvirCpu), promLabels...)
-	ch <- prometheus.MustNewConstMetric(libvirtDomainInfoCpuTimeDesc, prometheus.CounterValue, float64(rcputime)/1e9, promLabels...)
+	ch <- promLabels.metric(libvirtDomainInfoMaxMemDesc, prometheus.GaugeValue, float64(rmaxmem)*1024)
+	ch <- promLabels.metric(libvirtDomainInfoMemoryDesc, prometheus.GaugeValue, float64(rmemory)*1024)
+	ch <- promLabels.metric(libvirtDomainInfoNrVirtCpuDesc, prometheus.GaugeValue, float64(rvirCpu))
+	ch <- promLabels.metric(libvirtDomainInfoCpuTimeDesc, prometheus.CounterValue, float64(rcputime)/1e9)
 
 	var isActive int32
-	if isActive, err = l.DomainIsActive(domain.libvirtDomain); err != nil {
-		_ = level.Error(logger).Log("err", "failed to get active status of domain", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+	if domain.stats != nil {
+		if domain.stats.isActive() {
+			isActive = 1
+		}
+	} else if isActive, err = l.DomainIsActive(domain.libvirtDomain); libvirt.IsNotFound(err) {
+		return err
+	} else if err != nil {
+		_ = level.Error(logger).Log("err", "failed to get active status of domain", "domain", domain.libvirtDomain.Name, "msg", err)
 		return err
 	}
 	if isActive != 1 {
-		_ = level.Debug(logger).Log("debug", "domain is not active, skipping", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name)
+		_ = level.Debug(logger).Log("debug", "domain is not active, skipping", "domain", domain.libvirtDomain.Name)
 		return nil
 	}
 
-	for _, collectFunc := range []collectFunc{CollectDomainBlockDeviceInfo, CollectDomainNetworkInfo, CollectDomainMemoryStatInfo, CollectDomainVCPUInfo} {
-		if err = collectFunc(ch, l, domain, promLabels, logger); err != nil {
-			_ = level.Warn(logger).Log("warn", "failed to collect some domain info", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+	domain.maxMemory = rmaxmem
+
+	for _, c := range collectors {
+		if err = c.domain(ch, l, domain, promLabels, logger); libvirt.IsNotFound(err) {
+			// the domain is gone, there is nothing left to collect
+			return err
+		} else if err != nil {
+			_ = level.Warn(logger).Log("warn", "failed to collect some domain info", "collector", c.name, "domain", domain.libvirtDomain.Name, "msg", err)
 		}
 	}
 
 	return nil
 }
 
-type diskCache struct {
-    ReadBytes float64
-    ReadRequests float64
-    WriteBytes float64
-    WriteRequests float64
-    Timestamp time.Time
-}
-var diskTimeCache = make(map[string]diskCache)
-
-func CollectDomainBlockDeviceInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels []string, logger log.Logger) (err error) {
+func CollectDomainBlockDeviceInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, logger log.Logger) (err error) {
 
 	// Report block device statistics.
 
@@ -589,55 +1037,116 @@ func CollectDomainBlockDeviceInfo(ch chan<- prometheus.Metric, l *libvirt.Libvir
 		}
 
 		var rRdReq, rRdBytes, rWrReq, rWrBytes int64
-		if rRdReq, rRdBytes, rWrReq, rWrBytes, _, err = l.DomainBlockStats(domain.libvirtDomain, disk.Target.Device); err != nil {
-			_ = level.Warn(logger).Log("warn", "failed to get DomainBlockStats", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+		if domain.stats != nil {
+			rRdReq, rRdBytes, rWrReq, rWrBytes, err = domain.stats.blockStats(disk.Target.Device)
+		} else {
+			rRdReq, rRdBytes, rWrReq, rWrBytes, _, err = l.DomainBlockStats(domain.libvirtDomain, disk.Target.Device)
+		}
+		if err != nil {
+			_ = level.Warn(logger).Log("warn", "failed to get DomainBlockStats", "domain", domain.libvirtDomain.Name, "msg", err)
 			return err
 		}
 
-		promDiskLabels := append(promLabels, disk.Target.Device)
-		ch <- prometheus.MustNewConstMetric(
+		promDiskLabels := []string{disk.Target.Device}
+		ch <- promLabels.metric(
 			libvirtDomainBlockStatsRdBytesDesc,
 			prometheus.CounterValue,
 			float64(rRdBytes),
 			promDiskLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainBlockStatsRdReqDesc,
 			prometheus.CounterValue,
 			float64(rRdReq),
 			promDiskLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainBlockStatsWrBytesDesc,
 			prometheus.CounterValue,
 			float64(rWrBytes),
 			promDiskLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainBlockStatsWrReqDesc,
 			prometheus.CounterValue,
 			float64(rWrReq),
 			promDiskLabels...)
 
-	        _, capacityBytes, _, err := l.DomainGetBlockInfo(domain.libvirtDomain, disk.Target.Device, 0)
-		if err != nil {
-                        _ = level.Warn(logger).Log("warn", "failed to get BlockInfo", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
-                        return err
-                }
+		var capacityBytes uint64
+		var readTotalTime, writeTotalTime float64
+		if domain.stats != nil {
+			var rdTimes, wrTimes int64
+			capacityBytes, rdTimes, wrTimes = domain.stats.blockExtra(disk.Target.Device)
+			readTotalTime, writeTotalTime = float64(rdTimes), float64(wrTimes)
+		} else {
+			_, capacityBytes, _, err = l.DomainGetBlockInfo(domain.libvirtDomain, disk.Target.Device, 0)
+			if err != nil {
+				_ = level.Warn(logger).Log("warn", "failed to get BlockInfo", "domain", domain.libvirtDomain.Name, "msg", err)
+				return err
+			}
+
+			blockStats, _, err := l.DomainBlockStatsFlags(domain.libvirtDomain, disk.Target.Device, 10, 0)
+			if err != nil {
+				_ = level.Warn(logger).Log("warn", "failed to get DomainBlockStats", "domain", domain.libvirtDomain.Name, "msg", err)
+				return err
+			}
+			for _, param := range blockStats {
+				if param.Field == "rd_total_times" {
+					readTotalTime = float64(param.Value.I.(int64)) // Convert to float64 for Prometheus
+				}
+				if param.Field == "wr_total_times" {
+					writeTotalTime = float64(param.Value.I.(int64)) // Convert to float64 for Prometheus
+				}
+			}
+		}
 
+		ch <- promLabels.metric(
+                        libvirtDomainBlockCapacityBytesDesc,
+                        prometheus.GaugeValue,
+                        float64(capacityBytes),
+                        promDiskLabels...)
+
+
+	        // Total Read/Write time
+		ch <- promLabels.metric(
+                        libvirtDomainBlockRdTotalTimeSecondsDesc,
+                        prometheus.CounterValue,
+                        float64(readTotalTime),
+                        promDiskLabels...)
+		ch <- promLabels.metric(
+                        libvirtDomainBlockWrTotalTimeSecondsDesc,
+                        prometheus.CounterValue,
+                        float64(writeTotalTime),
+                        promDiskLabels...)
+
+		promDiskInfoLabels := []string{disk.Type, disk.Target.Bus, disk.Driver.Name, disk.Driver.Type, disk.Driver.Cache, disk.Driver.Discard, disk.Source.Path(), disk.Source.Protocol, disk.Target.Device, disk.Serial}
+		ch <- promLabels.metric(
+			libvirtDomainBlockStatsInfo,
+			prometheus.GaugeValue,
+			float64(1),
+			promDiskInfoLabels...)
+	}
+	return
+}
+
+// CollectDomainBlockIOTuneInfo reports the I/O tune limits of every disk and
+// the throughput relative to them over every rate window. DomainGetBlockIOTune
+// is called once per disk, so this collector is the most expensive one on
+// large hosts.
+func CollectDomainBlockIOTuneInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, logger log.Logger) (err error) {
+	for _, disk := range domain.libvirtSchema.Devices.Disks {
+		if disk.Device == "cdrom" || disk.Device == "fd" {
+			continue
+		}
+
+		promDiskLabels := []string{disk.Target.Device}
                 blockIOTune, _, err := l.DomainGetBlockIOTune(domain.libvirtDomain, libvirt.OptString{disk.Target.Device}, 30, 0)
                 if err != nil {
-                        _ = level.Warn(logger).Log("warn", "failed to get DomainBlockIOTune", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+                        _ = level.Warn(logger).Log("warn", "failed to get DomainBlockIOTune", "domain", domain.libvirtDomain.Name, "msg", err)
                         return err
                 }
 
 
-                blockStats, _, err := l.DomainBlockStatsFlags(domain.libvirtDomain, disk.Target.Device, 10, 0)
-		if err != nil {
-			_ = level.Warn(logger).Log("warn", "failed to get DomainBlockStats", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
-                        return err
-	        }
-
 		var totalBytesSec, readBytesSec, writeBytesSec, totalIopsSec, writeIopsSec, readIopsSec float64
                 for _, param := range blockIOTune {
                        if param.Field == "total_bytes_sec" {
@@ -660,173 +1169,85 @@ func CollectDomainBlockDeviceInfo(ch chan<- prometheus.Metric, l *libvirt.Libvir
                        }
                 }
 
-		var readTotalTime, writeTotalTime float64
-		for _, param := range blockStats {
-                       if param.Field == "rd_total_times" {
-                           readTotalTime = float64(param.Value.I.(int64)) // Convert to float64 for Prometheus
-                       }
-                       if param.Field == "wr_total_times" {
-                               writeTotalTime = float64(param.Value.I.(int64)) // Convert to float64 for Prometheus
-                       }
-		}
-
-		ch <- prometheus.MustNewConstMetric(
-                        libvirtDomainBlockCapacityBytesDesc,
-                        prometheus.GaugeValue,
-                        float64(capacityBytes),
-                        promDiskLabels...)
-
-
                 // Throughput limits (bytes/sec)
-                ch <- prometheus.MustNewConstMetric(
+                ch <- promLabels.metric(
                         libvirtDomainBlockTotalBytesSecDesc,
 			prometheus.GaugeValue,
                         totalBytesSec,
                         promDiskLabels...)
 
-                ch <- prometheus.MustNewConstMetric(
+                ch <- promLabels.metric(
                         libvirtDomainBlockWriteBytesSecDesc,
                         prometheus.GaugeValue,
                         float64(writeBytesSec),
                         promDiskLabels...)
 
-                ch <- prometheus.MustNewConstMetric(
+                ch <- promLabels.metric(
                         libvirtDomainBlockReadBytesSecDesc,
                         prometheus.GaugeValue,
                         float64(readBytesSec),
                         promDiskLabels...)
 
                 // IOPS limits
-                ch <- prometheus.MustNewConstMetric(
+                ch <- promLabels.metric(
                         libvirtDomainBlockTotalIopsSecDesc,
                         prometheus.GaugeValue,
                         float64(totalIopsSec),
                         promDiskLabels...)
 
-                ch <- prometheus.MustNewConstMetric(
+                ch <- promLabels.metric(
                         libvirtDomainBlockWriteIopsSecDesc,
                         prometheus.GaugeValue,
                         float64(writeIopsSec),
                         promDiskLabels...)
 
-                ch <- prometheus.MustNewConstMetric(
+                ch <- promLabels.metric(
                         libvirtDomainBlockReadIopsSecDesc,
                         prometheus.GaugeValue,
                         float64(readIopsSec),
                         promDiskLabels...)
-	        // Total Read/Write time
-		ch <- prometheus.MustNewConstMetric(
-                        libvirtDomainBlockRdTotalTimeSecondsDesc,
-                        prometheus.CounterValue,
-                        float64(readTotalTime),
-                        promDiskLabels...)
-		ch <- prometheus.MustNewConstMetric(
-                        libvirtDomainBlockWrTotalTimeSecondsDesc,
-                        prometheus.CounterValue,
-                        float64(writeTotalTime),
-                        promDiskLabels...)
-		//Disk Usage Percent
-		currentTime := time.Now()
-
-		cached, exists := diskTimeCache[domain.domainName]
-		if !exists {
-			diskTimeCache[domain.domainName] = diskCache{
-				ReadBytes:  float64(rRdBytes),
-				ReadRequests: float64(rRdReq),
-				WriteBytes: float64(rWrBytes),
-				WriteRequests: float64(rWrReq),
-				Timestamp:  currentTime,
-			}
-			return err
+		// usage relative to the limits over the fixed rate windows
+		now := time.Now()
+		usage := []struct {
+			desc     *domainDesc
+			limit    float64
+			counters []string
+		}{
+			{libvirtDomainBlockReadRequestsPercentDesc, readIopsSec, []string{"rd.reqs"}},
+			{libvirtDomainBlockWriteRequestsPercentDesc, writeIopsSec, []string{"wr.reqs"}},
+			{libvirtDomainBlockTotalRequestsPercentDesc, totalIopsSec, []string{"rd.reqs", "wr.reqs"}},
+			{libvirtDomainBlockReadBytesPercentDesc, readBytesSec, []string{"rd.bytes"}},
+			{libvirtDomainBlockWriteBytesPercentDesc, writeBytesSec, []string{"wr.bytes"}},
+			{libvirtDomainBlockTotalBytesPercentDesc, totalBytesSec, []string{"rd.bytes", "wr.bytes"}},
 		}
-		timeDelta := currentTime.Sub(cached.Timestamp).Seconds()
-		deltaReadBytes := float64(rRdBytes) - cached.ReadBytes
-		deltaReadRequests := float64(rRdReq) - cached.ReadRequests
-		deltaWriteBytes := float64(rWrBytes) - cached.WriteBytes
-		deltaWriteRequests := float64(rWrReq) - cached.WriteRequests
-		deltaTotalRequests := deltaReadRequests + deltaWriteRequests 
-
-		diskTimeCache[domain.domainName] = diskCache{
-			ReadBytes:  float64(rRdBytes),
-			ReadRequests: float64(rRdReq),
-			WriteBytes: float64(rWrBytes),
-			WriteRequests: float64(rWrReq),
-			Timestamp:  currentTime,
-		}
-
-		if readIopsSec != 0 {
-                        diskReadRequestsSec := float64(deltaReadRequests) / timeDelta
-                        diskReadRequestsPercent := ( float64(diskReadRequestsSec) / readIopsSec ) * float64(100)
-
-                        ch <- prometheus.MustNewConstMetric(
-                                libvirtDomainBlockReadRequestsPercentDesc,
-                                prometheus.GaugeValue,
-                                float64(diskReadRequestsPercent),
-                                promDiskLabels...)
-                }
-		if writeIopsSec != 0 {
-                        diskWriteRequestsSec := float64(deltaWriteRequests) / timeDelta
-                        diskWriteRequestsPercent := ( float64(diskWriteRequestsSec) / writeIopsSec ) * float64(100)
-
-                        ch <- prometheus.MustNewConstMetric(
-                                libvirtDomainBlockWriteRequestsPercentDesc,
-                                prometheus.GaugeValue,
-                                float64(diskWriteRequestsPercent),
-                                promDiskLabels...)
-                }
-		if totalIopsSec != 0 {
-                        diskTotalRequestsSec := float64(deltaTotalRequests) / timeDelta
-                        diskTotalRequestsPercent := ( float64(diskTotalRequestsSec) / totalIopsSec ) * float64(100)
-
-                        ch <- prometheus.MustNewConstMetric(
-                                libvirtDomainBlockTotalRequestsPercentDesc,
-                                prometheus.GaugeValue,
-                                float64(diskTotalRequestsPercent),
-                                promDiskLabels...)
-                }
-		if readBytesSec != 0 {
-			diskReadBytesSec := float64(deltaReadBytes) / timeDelta
-			diskReadBytesPercent := ( float64(diskReadBytesSec) / readBytesSec ) * float64(100)
-
-                        ch <- prometheus.MustNewConstMetric(
-                                libvirtDomainBlockReadBytesPercentDesc,
-                                prometheus.GaugeValue,
-                                float64(diskReadBytesPercent),
-                                promDiskLabels...)
-                }
-
-		if writeBytesSec != 0 {
-			diskWriteBytesSec := float64(deltaWriteBytes) / timeDelta
-			diskWriteBytesPercent := ( float64(diskWriteBytesSec) / writeBytesSec ) * float64(100)
-
-                        ch <- prometheus.MustNewConstMetric(
-                                libvirtDomainBlockWriteBytesPercentDesc,
-                                prometheus.GaugeValue,
-                                float64(diskWriteBytesPercent),
-                                promDiskLabels...)
-                }
-		if totalBytesSec != 0 {
-			deltaTotalBytes := deltaReadBytes + deltaWriteBytes
-			diskTotalBytesPercent := ( deltaTotalBytes / totalBytesSec )  * float64(100)
-			ch <- prometheus.MustNewConstMetric(
-				libvirtDomainBlockTotalBytesPercentDesc,
-				prometheus.GaugeValue,
-				float64(diskTotalBytesPercent),
-                                promDiskLabels...)
+		for _, u := range usage {
+			if u.limit == 0 {
+				continue
+			}
+			for _, window := range domain.rates.windowList() {
+				var total float64
+				ok := true
+				for _, counter := range u.counters {
+					key := rateKey{uuid: domain.libvirtDomain.UUID, device: disk.Target.Device, counter: counter}
+					rate, found := domain.rates.rate(key, window.duration, now)
+					total += rate
+					ok = ok && found
+				}
+				if !ok {
+					continue
+				}
+				ch <- promLabels.metric(
+					u.desc,
+					prometheus.GaugeValue,
+					total/u.limit*100,
+					append(promDiskLabels, window.label)...)
+			}
 		}
-
-
-		promDiskInfoLabels := append(promLabels, disk.Type, disk.Target.Bus, disk.Driver.Name, disk.Driver.Type, disk.Driver.Cache, disk.Driver.Discard, disk.Source.File, disk.Source.Protocol, disk.Target.Device, disk.Serial)
-		ch <- prometheus.MustNewConstMetric(
-			libvirtDomainBlockStatsInfo,
-			prometheus.GaugeValue,
-			float64(1),
-			promDiskInfoLabels...)
 	}
 	return
 }
 
-func CollectDomainNetworkInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels []string, logger log.Logger) (err error) {
+func CollectDomainNetworkInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, logger log.Logger) (err error) {
 
 	// Report network interface statistics.
 	for _, iface := range domain.libvirtSchema.Devices.Interfaces {
@@ -834,63 +1255,68 @@ func CollectDomainNetworkInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, d
 			continue
 		}
 		var rRxBytes, rRxPackets, rRxErrs, rRxDrop, rTxBytes, rTxPackets, rTxErrs, rTxDrop int64
-		if rRxBytes, rRxPackets, rRxErrs, rRxDrop, rTxBytes, rTxPackets, rTxErrs, rTxDrop, err = l.DomainInterfaceStats(domain.libvirtDomain, iface.Target.Device); err != nil {
-			_ = level.Warn(logger).Log("warn", "failed to get DomainInterfaceStats", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+		if domain.stats != nil {
+			rRxBytes, rRxPackets, rRxErrs, rRxDrop, rTxBytes, rTxPackets, rTxErrs, rTxDrop, err = domain.stats.interfaceStats(iface.Target.Device)
+		} else {
+			rRxBytes, rRxPackets, rRxErrs, rRxDrop, rTxBytes, rTxPackets, rTxErrs, rTxDrop, err = l.DomainInterfaceStats(domain.libvirtDomain, iface.Target.Device)
+		}
+		if err != nil {
+			_ = level.Warn(logger).Log("warn", "failed to get DomainInterfaceStats", "domain", domain.libvirtDomain.Name, "msg", err)
 			return err
 		}
 		newAliasName := strings.Replace(iface.Alias.Name, "net", "eth", 1)
 
-		promInterfaceLabels := append(promLabels, iface.Target.Device, newAliasName)
-		ch <- prometheus.MustNewConstMetric(
+		promInterfaceLabels := []string{iface.Target.Device, newAliasName}
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceRxBytesDesc,
 			prometheus.CounterValue,
 			float64(rRxBytes),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceRxPacketsDesc,
 			prometheus.CounterValue,
 			float64(rRxPackets),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceRxErrsDesc,
 			prometheus.CounterValue,
 			float64(rRxErrs),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceRxDropDesc,
 			prometheus.CounterValue,
 			float64(rRxDrop),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceTxBytesDesc,
 			prometheus.CounterValue,
 			float64(rTxBytes),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceTxPacketsDesc,
 			prometheus.CounterValue,
 			float64(rTxPackets),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceTxErrsDesc,
 			prometheus.CounterValue,
 			float64(rTxErrs),
 			promInterfaceLabels...)
 
-		ch <- prometheus.MustNewConstMetric(
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceTxDropDesc,
 			prometheus.CounterValue,
 			float64(rTxDrop),
 			promInterfaceLabels...)
 
-		promInterfaceInfoLabels := append(promLabels, iface.Type, iface.Source.Bridge, iface.Target.Device, iface.MAC.Address, iface.Model.Type, iface.MTU.Size, newAliasName)
-		ch <- prometheus.MustNewConstMetric(
+		promInterfaceInfoLabels := []string{iface.Type, iface.Source.Bridge, iface.Target.Device, iface.MAC.Address, iface.Model.Type, iface.MTU.Size, newAliasName}
+		ch <- promLabels.metric(
 			libvirtDomainInterfaceInfo,
 			prometheus.GaugeValue,
 			float64(1),
@@ -899,11 +1325,13 @@ func CollectDomainNetworkInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, d
 	return
 }
 
-func CollectDomainMemoryStatInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels []string, logger log.Logger) (err error) {
+func CollectDomainMemoryStatInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, logger log.Logger) (err error) {
 	//collect stat info
 	var rStats []libvirt.DomainMemoryStat
-	if rStats, err = l.DomainMemoryStats(domain.libvirtDomain, uint32(libvirt.DomainMemoryStatNr), 0); err != nil {
-		_ = level.Warn(logger).Log("warn", "failed to get DomainMemoryStats", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+	if domain.stats != nil {
+		rStats = domain.stats.memoryStats()
+	} else if rStats, err = l.DomainMemoryStats(domain.libvirtDomain, uint32(libvirt.DomainMemoryStatNr), 0); err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get DomainMemoryStats", "domain", domain.libvirtDomain.Name, "msg", err)
 		return err
 	}
 
@@ -911,217 +1339,154 @@ func CollectDomainMemoryStatInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt
 	for _, stat := range rStats {
 		switch stat.Tag {
 		case int32(libvirt.DomainMemoryStatSwapIn):
-			ch <- prometheus.MustNewConstMetric(
+			ch <- promLabels.metric(
 				libvirtDomainMemoryStatsSwapInBytesDesc,
 				prometheus.GaugeValue,
-				float64(stat.Val)*1024,
-				promLabels...)
+				float64(stat.Val)*1024)
 		case int32(libvirt.DomainMemoryStatSwapOut):
-			ch <- prometheus.MustNewConstMetric(
+			ch <- promLabels.metric(
 				libvirtDomainMemoryStatsSwapOutBytesDesc,
 				prometheus.GaugeValue,
-				float64(stat.Val)*1024,
-				promLabels...)
+				float64(stat.Val)*1024)
 		case int32(libvirt.DomainMemoryStatUnused):
-			ch <- prometheus.MustNewConstMetric(
+			ch <- promLabels.metric(
 				libvirtDomainMemoryStatsUnusedBytesDesc,
 				prometheus.GaugeValue,
-				float64(stat.Val*1024),
-				promLabels...)
+				float64(stat.Val*1024))
 			freeMemoryBytes = stat.Val*1024
 		case int32(libvirt.DomainMemoryStatAvailable):
-			ch <- prometheus.MustNewConstMetric(
+			ch <- promLabels.metric(
 				libvirtDomainMemoryStatsAvailableInBytesDesc,
 				prometheus.GaugeValue,
-				float64(stat.Val*1024),
-				promLabels...)
+				float64(stat.Val*1024))
 		case int32(libvirt.DomainMemoryStatUsable):
-			ch <- prometheus.MustNewConstMetric(
+			ch <- promLabels.metric(
 				libvirtDomainMemoryStatsUsableBytesDesc,
 				prometheus.GaugeValue,
-				float64(stat.Val*1024),
-				promLabels...)
+				float64(stat.Val*1024))
 		case int32(libvirt.DomainMemoryStatRss):
-			ch <- prometheus.MustNewConstMetric(
+			ch <- promLabels.metric(
 				libvirtDomainMemoryStatsRssBytesDesc,
 				prometheus.GaugeValue,
-				float64(stat.Val*1024),
-				promLabels...)
+				float64(stat.Val*1024))
 		case int32(libvirt.DomainMemoryStatDiskCaches):
 			diskCached = stat.Val*1024
-                        ch <- prometheus.MustNewConstMetric(
+                        ch <- promLabels.metric(
                                 libvirtDomainMemoryStatDiskCachesBytesDesc,
                                 prometheus.GaugeValue,
-                                float64(stat.Val*1024),
-                                promLabels...)
+                                float64(stat.Val*1024))
                 }
 	}
 			var freeMemoryPercent, usedMemoryPercent float64
-			maxMemoryBytes := float64(rmaxmem)*1024
+			maxMemoryBytes := float64(domain.maxMemory)*1024
 			freeMemoryPercent = (float64(freeMemoryBytes) / maxMemoryBytes) *float64(100)
 			usedMemoryPercent = 100 - freeMemoryPercent
 			usednocache :=     maxMemoryBytes - float64(freeMemoryBytes) - float64(diskCached)
 			usednocachePercent := (float64(usednocache) / maxMemoryBytes) * float64(100)
-                        ch <- prometheus.MustNewConstMetric(
+                        ch <- promLabels.metric(
                               libvirtDomainMemoryStatUsedPercentDesc,
                               prometheus.GaugeValue,
-                              float64(usedMemoryPercent),
-                              promLabels...)
-			ch <- prometheus.MustNewConstMetric(
+                              float64(usedMemoryPercent))
+			ch <- promLabels.metric(
                               libvirtDomainMemoryStatFreePercentDesc,
                               prometheus.GaugeValue,
-                              float64(freeMemoryPercent),
-                              promLabels...)
-			ch <- prometheus.MustNewConstMetric(
+                              float64(freeMemoryPercent))
+			ch <- promLabels.metric(
                               libvirtDomainMemoryStatUsednocachePercentDesc,
                               prometheus.GaugeValue,
-                              float64(usednocachePercent),
-                              promLabels...)
+                              float64(usednocachePercent))
 	return
 }
 
-// Cache to store the previous vCPU times and their timestamps
-var vcpuTimeCache = make(map[string]map[int]vcpuCache)
-
-type vcpuCache struct {
-    lastTime uint64
-    lastDelay uint64
-    lastTimestamp time.Time
-}
-
-func CollectDomainVCPUInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels []string, logger log.Logger) (err error) {
+func CollectDomainVCPUInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, logger log.Logger) (err error) {
 	//collect domain vCPU stats
 	var stats []libvirt.DomainStatsRecord
 	// ConnectGetAllDomainStats expects a list of domains
 	var d []libvirt.Domain
 	d = append(d, domain.libvirtDomain)
 
-	if stats, err = l.ConnectGetAllDomainStats(d, uint32(libvirt.DomainStatsVCPU), 0); err != nil {
-		_ = level.Warn(logger).Log("warn", "failed to get vcpu stats", "domain", "instance_name", "project_id", "project_name", domain.libvirtDomain.Name, "msg", err)
+	if domain.stats != nil {
+		stats = []libvirt.DomainStatsRecord{{Dom: domain.libvirtDomain, Params: domain.stats.vcpuParams()}}
+	} else if stats, err = l.ConnectGetAllDomainStats(d, uint32(libvirt.DomainStatsVCPU), 0); err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get vcpu stats", "domain", domain.libvirtDomain.Name, "msg", err)
 		return err
 	}
 
-	// Get domain name
-	domainName := domain.libvirtDomain.Name
-	//Initialize caching for Prometheus metrics
-	if _, ok := vcpuTimeCache[domainName]; !ok {
-		vcpuTimeCache[domainName] = make(map[int]vcpuCache)
-	}
-
 
 	current := regexp.MustCompile("vcpu.current")
 	maximum := regexp.MustCompile("vcpu.maximum")
 	vcpu_metrics := regexp.MustCompile(`vcpu\.\d+\.\w+`)
+	now := time.Now()
 	for _, stat := range stats {
 		for _, param := range stat.Params {
 			switch true {
 			case current.MatchString(param.Field):
 				metric_value := param.Value.I.(uint32)
-				ch <- prometheus.MustNewConstMetric(
+				ch <- promLabels.metric(
 					libvirtDomainVCPUStatsCurrent,
 					prometheus.GaugeValue,
-					float64(metric_value),
-					promLabels...)
+					float64(metric_value))
 			case maximum.MatchString(param.Field):
 				metric_value := param.Value.I.(uint32)
-				ch <- prometheus.MustNewConstMetric(
+				ch <- promLabels.metric(
 					libvirtDomainVCPUStatsMaximum,
 					prometheus.GaugeValue,
-					float64(metric_value),
-					promLabels...)
+					float64(metric_value))
 			case vcpu_metrics.MatchString(param.Field):
 				r := regexp.MustCompile(`vcpu\.(\d+)\.(\w+)`)
 				match := r.FindStringSubmatch(param.Field)
-				promVCPULabels := append(promLabels, match[1])
-
-				vcpuIndex, _ := strconv.Atoi(match[1])
-				// Get the current time
-                                currentTime := time.Now()
-
-                                // Retrieve the cached data for this vCPU, if it exists
-                                cached, exists := vcpuTimeCache[domainName][vcpuIndex]
+				promVCPULabels := []string{match[1]}
+
+				// emitPercent reports the share of wall-clock time spent
+				// in the counter over every rate window
+				emitPercent := func(desc *domainDesc) {
+					key := rateKey{uuid: domain.libvirtDomain.UUID, device: match[1], counter: match[2]}
+					for _, window := range domain.rates.windowList() {
+						if nsPerSec, ok := domain.rates.rate(key, window.duration, now); ok {
+							ch <- promLabels.metric(
+								desc,
+								prometheus.GaugeValue,
+								nsPerSec/1e9*100,
+								append(promVCPULabels, window.label)...)
+						}
+					}
+				}
 
 				switch match[2] {
 				case "state":
 					metric_value := param.Value.I.(int32)
-					ch <- prometheus.MustNewConstMetric(
+					ch <- promLabels.metric(
 						libvirtDomainVCPUStatsState,
 						prometheus.GaugeValue,
 						float64(metric_value),
 						promVCPULabels...)
 				case "time":
 					metric_value := param.Value.I.(uint64)
-					ch <- prometheus.MustNewConstMetric(
+					ch <- promLabels.metric(
 						libvirtDomainVCPUStatsTime,
 						prometheus.CounterValue,
 						float64(metric_value)/1e9,
 						promVCPULabels...)
 
-                                        if exists {
-                                                // Calculate the time difference between the current and previous metric collection
-                                                timeDelta := currentTime.Sub(cached.lastTimestamp).Seconds()
-
-                                                if timeDelta > 0 {
-                                                                 // Calculate the vCPU time delta
-                                                                 cputimeDelta := float64(metric_value - cached.lastTime) / 1e9 // Convert nanoseconds to seconds
-
-                                                                 // Calculate the CPU usage percentage over the measured interval
-                                                                 cpuPercent := (cputimeDelta / timeDelta) * 100
-
-                                                                 // Emit the metric for vCPU system percent
-                                                                 promVCPULabels := append(promLabels, match[1])
-                                                                 ch <- prometheus.MustNewConstMetric(
-                                                                         libvirtDomainVCPUStatsSysPercent,
-                                                                         prometheus.GaugeValue,
-                                                                         cpuPercent,
-                                                                         promVCPULabels...)
-                                                }
-                                        }
-
-                                        // Store the current time and vCPU time value in the cache for future comparisons
-                                        vcpuTimeCache[domainName][vcpuIndex] = vcpuCache{
-                                                     lastTime:      metric_value,
-						     lastDelay:     cached.lastDelay,
-                                                     lastTimestamp: currentTime,
-                                        }
+					// share of wall-clock time the vCPU was running
+					emitPercent(libvirtDomainVCPUStatsSysPercent)
 				case "wait":
 					metric_value := param.Value.I.(uint64)
-					ch <- prometheus.MustNewConstMetric(
+					ch <- promLabels.metric(
 						libvirtDomainVCPUStatsWait,
 						prometheus.CounterValue,
 						float64(metric_value)/1e9,
 						promVCPULabels...)
-					// Calculate the delay (steal) metric similarly
-                                        if exists {
-                                                   timeDelta := currentTime.Sub(cached.lastTimestamp).Seconds()
-
-                                                   if timeDelta > 0 {
-                                                                    delayDelta := float64(metric_value - cached.lastDelay) / float64(1e9)
-
-                                                                    // Calculate steal percent
-                                                                    stealPercent := (delayDelta / timeDelta) * 100
-
-                                                                    // Emit the metric for vCPU steal percent
-                                                                    ch <- prometheus.MustNewConstMetric(
-                                                                            libvirtDomainVCPUStatsStealPercent,
-                                                                            prometheus.GaugeValue,
-                                                                            stealPercent,
-                                                                            promVCPULabels...)
-				                   }
-                                                   // Store the current time and vCPU time value in the cache for future comparisons
-                                                   vcpuTimeCache[domainName][vcpuIndex] = vcpuCache{
-                                                               lastTime:      cached.lastTime,
-                                                               lastDelay:     metric_value,
-                                                               lastTimestamp: currentTime,
-					           }
-				        }
 				case "delay":
 					metric_value := param.Value.I.(uint64)
-					ch <- prometheus.MustNewConstMetric(
+					ch <- promLabels.metric(
 						libvirtDomainVCPUStatsDelay,
 						prometheus.CounterValue,
 						float64(metric_value)/1e9,
 						promVCPULabels...)
+					// share of wall-clock time the vCPU was runnable but
+					// waiting for a host CPU, i.e. steal time
+					emitPercent(libvirtDomainVCPUStatsStealPercent)
 				}
 			}
 		}
@@ -1129,6 +1494,28 @@ func CollectDomainVCPUInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, doma
 	return
 }
 
+// CollectStoragePools reports the metrics of all storage pools.
+// see https://libvirt.org/html/libvirt-libvirt-storage.html
+func CollectStoragePools(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error) {
+	var pools []libvirt.StoragePool
+	if pools, _, err = l.ConnectListAllStoragePools(1, 0); err != nil {
+		_ = level.Error(logger).Log("err", "failed to collect storage pools", "msg", err)
+		return err
+	}
+	// one failing pool does not hide the others
+	for _, pool := range pools {
+		if poolErr := CollectStoragePoolInfo(ch, l, pool, logger); isLibvirtErr(poolErr, libvirt.ErrNoStoragePool, libvirt.ErrOperationInvalid) {
+			// undefined (no storage pool) or stopped (operation invalid) since it
+			// was listed
+			continue
+		} else if poolErr != nil {
+			_ = level.Error(logger).Log("err", "failed to collect storage pool info", "pool", pool.Name, "msg", poolErr)
+			err = poolErr
+		}
+	}
+	return err
+}
+
 func CollectStoragePoolInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, pool libvirt.StoragePool, logger log.Logger) (err error) {
 	// Report storage pool metrics
 	var rState uint8
@@ -1167,77 +1554,25 @@ func CollectStoragePoolInfo(ch chan<- prometheus.Metric, l *libvirt.Libvirt, poo
 // Describe returns metadata for all Prometheus metrics that may be exported.
 func (e *LibvirtExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- libvirtUpDesc
+	ch <- libvirtDomainsTimedOutDesc
+	ch <- libvirtDomainErrorsDesc
+	ch <- libvirtErrorsDesc
+	ch <- libvirtCollectorDurationDesc
+	ch <- libvirtCollectorSuccessDesc
+	e.conn.describe(ch)
+	if e.poller != nil {
+		e.poller.describe(ch)
+	}
+	if e.lifecycleEvents {
+		e.events.describe(ch)
+	}
+	if e.domainCache != nil {
+		e.domainCache.describe(ch)
+	}
 	ch <- libvirtDomainNumbers
+	ch <- libvirtDomainsFilteredDesc
 
-	ch <- libvirtDomainInfoDesc
-	ch <- libvirtDomainOpenstackInfoDesc
+	e.labels.describe(ch, baseDomainDescs...)
 
-	//domain info
-	ch <- libvirtDomainState
-	ch <- libvirtDomainInfoMaxMemDesc
-	ch <- libvirtDomainInfoMemoryDesc
-	ch <- libvirtDomainInfoNrVirtCpuDesc
-	ch <- libvirtDomainInfoCpuTimeDesc
-
-	//domain block
-	ch <- libvirtDomainBlockStatsInfo
-	ch <- libvirtDomainBlockStatsRdBytesDesc
-	ch <- libvirtDomainBlockStatsRdReqDesc
-	ch <- libvirtDomainBlockStatsWrBytesDesc
-	ch <- libvirtDomainBlockStatsWrReqDesc
-	ch <- libvirtDomainBlockCapacityBytesDesc
-	ch <- libvirtDomainBlockRdTotalTimeSecondsDesc
-        ch <- libvirtDomainBlockWrTotalTimeSecondsDesc
-	ch <- libvirtDomainBlockTotalBytesSecDesc
-	ch <- libvirtDomainBlockReadBytesSecDesc
-	ch <- libvirtDomainBlockWriteBytesSecDesc
-	ch <- libvirtDomainBlockTotalIopsSecDesc
-	ch <- libvirtDomainBlockReadIopsSecDesc
-	ch <- libvirtDomainBlockWriteIopsSecDesc
-	ch <- libvirtDomainBlockReadBytesPercentDesc
-        ch <- libvirtDomainBlockWriteBytesPercentDesc
-        ch <- libvirtDomainBlockTotalBytesPercentDesc
-	ch <- libvirtDomainBlockReadRequestsPercentDesc
-	ch <- libvirtDomainBlockWriteRequestsPercentDesc
-	ch <- libvirtDomainBlockTotalRequestsPercentDesc
-
-	//domain interface
-	ch <- libvirtDomainInterfaceInfo
-	ch <- libvirtDomainInterfaceRxBytesDesc
-	ch <- libvirtDomainInterfaceRxPacketsDesc
-	ch <- libvirtDomainInterfaceRxErrsDesc
-	ch <- libvirtDomainInterfaceRxDropDesc
-	ch <- libvirtDomainInterfaceTxBytesDesc
-	ch <- libvirtDomainInterfaceTxPacketsDesc
-	ch <- libvirtDomainInterfaceTxErrsDesc
-	ch <- libvirtDomainInterfaceTxDropDesc
-
-	//domain mem stat
-	ch <- libvirtDomainMemoryStatsSwapInBytesDesc
-	ch <- libvirtDomainMemoryStatsSwapOutBytesDesc
-	ch <- libvirtDomainMemoryStatsUnusedBytesDesc
-	ch <- libvirtDomainMemoryStatsAvailableInBytesDesc
-	ch <- libvirtDomainMemoryStatsUsableBytesDesc
-	ch <- libvirtDomainMemoryStatsRssBytesDesc
-	ch <- libvirtDomainMemoryStatUsedPercentDesc
-	ch <- libvirtDomainMemoryStatDiskCachesBytesDesc
-	ch <- libvirtDomainMemoryStatUsedPercentDesc
-	ch <- libvirtDomainMemoryStatFreePercentDesc
-	ch <- libvirtDomainMemoryStatUsednocachePercentDesc
-
-	//domain vcpu stats
-	ch <- libvirtDomainVCPUStatsCurrent
-	ch <- libvirtDomainVCPUStatsMaximum
-	ch <- libvirtDomainVCPUStatsState
-	ch <- libvirtDomainVCPUStatsTime
-	ch <- libvirtDomainVCPUStatsWait
-	ch <- libvirtDomainVCPUStatsDelay
-	ch <- libvirtDomainVCPUStatsSysPercent
-	ch <- libvirtDomainVCPUStatsStealPercent
-
-	//storage pool metrics
-	ch <- libvirtStoragePoolState
-	ch <- libvirtStoragePoolCapacity
-	ch <- libvirtStoragePoolAllocation
-	ch <- libvirtStoragePoolAvailable
+	e.collectors.describe(ch, e.labels)
 }
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"testing"
 
-	libvirt_schema "github.com/inovex/prometheus-libvirt-exporter/libvirt_schema"
+	libvirt_schema "github.com/thongth1998/libvirt-exporter/libvirt_schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -161,6 +161,9 @@ func TestUnmarshal(t *testing.T) {
 	assert.Equal(nil, r.OSMetadata.Type.Value, "hvm")
 	assert.Equal(nil, r.OSMetadata.Type.Machine, "pc-i440fx-rhel7.3.0")
 	assert.Equal(nil, r.OSMetadata.Type.Arch, "x86_64")
+	// block disks have their device as path, to be joined with storage volumes
+	assert.Equal(t, "/dev/disk/by-path/ip-10.110.20.107:3260-iscsi-iqn.2010-10.org.openstack:volume-9bdce751-6bd2-495a-abc0-cfedbdfdc8be-lun-0", r.Devices.Disks[0].Source.Path())
+	assert.Equal(t, "/var/lib/nova/instances/a6b57d2e-dad0-4860-9104-6eb072935126/disk.swap", r.Devices.Disks[1].Source.Path())
 	fmt.Printf("xml name=%#v\n", r.Metadata.NovaInstance.XMLName)
 	fmt.Printf("nova name=%#v\n", r.Metadata.NovaInstance.Name)
 	fmt.Printf("nova =%#v\n", r.Metadata)
@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/common/model"
+)
+
+// rateKey identifies one cumulative counter of one device of a domain.
+type rateKey struct {
+	uuid    libvirt.UUID
+	device  string
+	counter string
+}
+
+type rateSample struct {
+	value float64
+	at    time.Time
+}
+
+// rateWindow is a window utilization is computed over, e.g. 1m or 5m.
+type rateWindow struct {
+	duration time.Duration
+	// label is the value of the window label.
+	label string
+}
+
+func newRateWindows(windows []time.Duration) []rateWindow {
+	rateWindows := make([]rateWindow, 0, len(windows))
+	for _, w := range windows {
+		rateWindows = append(rateWindows, rateWindow{duration: w, label: model.Duration(w).String()})
+	}
+	return rateWindows
+}
+
+// rateTracker keeps the recent history of cumulative libvirt counters, as
+// sampled at a fixed interval by the sampler, and derives per-second rates
+// over fixed windows from it. Since scrapes only read the history, the rates
+// do not depend on who scrapes how often.
+type rateTracker struct {
+	interval time.Duration
+	windows  []rateWindow
+
+	mu      sync.Mutex
+	samples map[rateKey][]rateSample
+}
+
+func newRateTracker(interval time.Duration, windows []time.Duration) *rateTracker {
+	return &rateTracker{
+		interval: interval,
+		windows:  newRateWindows(windows),
+		samples:  make(map[rateKey][]rateSample),
+	}
+}
+
+// windowList returns the windows rates are computed over, or none for a nil
+// tracker.
+func (r *rateTracker) windowList() []rateWindow {
+	if r == nil {
+		return nil
+	}
+	return r.windows
+}
+
+// maxWindow returns the longest window, which bounds the history kept.
+func (r *rateTracker) maxWindow() time.Duration {
+	var max time.Duration
+	for _, w := range r.windows {
+		if w.duration > max {
+			max = w.duration
+		}
+	}
+	return max
+}
+
+// add records a sample of the counter. A counter that went backwards, which
+// happens when the domain restarts or migrates to this host, starts a new
+// history.
+func (r *rateTracker) add(key rateKey, value float64, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := r.samples[key]
+	if n := len(history); n > 0 && value < history[n-1].value {
+		history = history[:0]
+	}
+	history = append(history, rateSample{value: value, at: at})
+
+	// keep one interval more than the longest window needs
+	oldest := at.Add(-r.maxWindow() - r.interval)
+	trim := 0
+	for trim < len(history)-1 && history[trim].at.Before(oldest) {
+		trim++
+	}
+	r.samples[key] = append(history[:0], history[trim:]...)
+}
+
+// rate returns the per-second rate of the counter over window as of now.
+// There is no rate until the history covers the window, nor when the latest
+// sample is stale because the sampler could not reach libvirt. rate on a nil
+// tracker never returns a rate.
+func (r *rateTracker) rate(key rateKey, window time.Duration, now time.Time) (float64, bool) {
+	if r == nil {
+		return 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := r.samples[key]
+	if len(history) < 2 {
+		return 0, false
+	}
+	latest := history[len(history)-1]
+	if now.Sub(latest.at) > 2*r.interval {
+		return 0, false
+	}
+
+	// the newest sample at least one window older than the latest one,
+	// allowing half an interval of jitter
+	target := latest.at.Add(-window + r.interval/2)
+	for i := len(history) - 2; i >= 0; i-- {
+		base := history[i]
+		if base.at.After(target) {
+			continue
+		}
+		return (latest.value - base.value) / latest.at.Sub(base.at).Seconds(), true
+	}
+	return 0, false
+}
+
+// retain evicts the history of domains that are no longer listed.
+func (r *rateTracker) retain(uuids []libvirt.UUID) {
+	listed := make(map[libvirt.UUID]bool, len(uuids))
+	for _, uuid := range uuids {
+		listed[uuid] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.samples {
+		if !listed[key.uuid] {
+			delete(r.samples, key)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateTracker(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	r := newRateTracker(10*time.Second, []time.Duration{time.Minute, 5 * time.Minute})
+	vda := rateKey{uuid: libvirt.UUID{1}, device: "vda", counter: "rd.reqs"}
+
+	assert.Equal(t, "1m", r.windows[0].label)
+	assert.Equal(t, "5m", r.windows[1].label)
+
+	// 10 requests per second for two minutes
+	for s := 0; s <= 120; s += 10 {
+		r.add(vda, float64(s*10), at(s))
+	}
+	rate, ok := r.rate(vda, time.Minute, at(120))
+	assert.True(t, ok)
+	assert.InDelta(t, 10, rate, 1e-9)
+	_, ok = r.rate(vda, 5*time.Minute, at(120))
+	assert.False(t, ok, "no rate until the history covers the window")
+
+	// the rate does not depend on when it is read
+	rate, ok = r.rate(vda, time.Minute, at(125))
+	assert.True(t, ok)
+	assert.InDelta(t, 10, rate, 1e-9)
+	_, ok = r.rate(vda, time.Minute, at(150))
+	assert.False(t, ok, "no rate from stale samples")
+
+	// the history is bounded by the longest window
+	for s := 130; s <= 600; s += 10 {
+		r.add(vda, float64(s*10), at(s))
+	}
+	assert.Len(t, r.samples[vda], 32)
+	rate, ok = r.rate(vda, 5*time.Minute, at(600))
+	assert.True(t, ok)
+	assert.InDelta(t, 10, rate, 1e-9)
+
+	// a counter reset starts a new history
+	r.add(vda, 50, at(610))
+	_, ok = r.rate(vda, time.Minute, at(610))
+	assert.False(t, ok)
+
+	other := rateKey{uuid: libvirt.UUID{2}, device: "vda", counter: "rd.reqs"}
+	r.add(other, 1, at(610))
+	r.retain([]libvirt.UUID{{2}})
+	assert.Len(t, r.samples, 1)
+
+	var nilTracker *rateTracker
+	_, ok = nilTracker.rate(vda, time.Minute, at(610))
+	assert.False(t, ok)
+	assert.Empty(t, nilTracker.windowList())
+}
+
+func TestSamplerRecord(t *testing.T) {
+	param := func(field string, v interface{}) libvirt.TypedParam {
+		return libvirt.TypedParam{Field: field, Value: libvirt.TypedParamValue{I: v}}
+	}
+	stats := newDomainStats([]libvirt.TypedParam{
+		param("block.count", uint32(1)),
+		param("block.0.name", "vda"),
+		param("block.0.rd.reqs", uint64(100)),
+		param("block.0.wr.bytes", uint64(4096)),
+		param("vcpu.current", uint32(1)),
+		param("vcpu.0.time", uint64(5e9)),
+		param("vcpu.0.delay", uint64(1e6)),
+		param("vcpu.0.wait", uint64(7)),
+	})
+	r := newRateTracker(time.Second, []time.Duration{time.Minute})
+	s := newSampler(nil, r, nil)
+	s.record(libvirt.UUID{1}, stats, time.Now())
+
+	uuid := libvirt.UUID{1}
+	assert.Len(t, r.samples, 4)
+	assert.Contains(t, r.samples, rateKey{uuid: uuid, device: "vda", counter: "rd.reqs"})
+	assert.Contains(t, r.samples, rateKey{uuid: uuid, device: "vda", counter: "wr.bytes"})
+	assert.Contains(t, r.samples, rateKey{uuid: uuid, device: "0", counter: "time"})
+	assert.Contains(t, r.samples, rateKey{uuid: uuid, device: "0", counter: "delay"})
+}
@@ -0,0 +1,186 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtConfigHashDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "config", "hash"),
+		"Hash of the loaded configuration file, 0 without one.",
+		nil,
+		nil)
+	libvirtConfigLastReloadSuccessfulDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "config", "last_reload_successful"),
+		"Whether the last configuration reload attempt was successful.",
+		nil,
+		nil)
+	libvirtConfigLastReloadSuccessTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "config", "last_reload_success_timestamp_seconds"),
+		"Unix timestamp of the last successful configuration reload.",
+		nil,
+		nil)
+)
+
+// BuildFunc creates the exporter for /metrics from a configuration.
+type BuildFunc func(config *Config) (*LibvirtExporter, error)
+
+// Reloader owns the configuration file and the exporter built from it.
+// Reload replaces both at once: a file that fails to load or an exporter that
+// fails to build leaves the running ones untouched.
+type Reloader struct {
+	path   string
+	build  BuildFunc
+	logger log.Logger
+
+	// reloading serialises reloads, so a slow one is never overtaken.
+	reloading sync.Mutex
+
+	// mu is held for reading while the exporter is scraped, so the old
+	// exporter is only closed once the scrapes using it have finished.
+	mu       sync.RWMutex
+	config   *Config
+	exporter *LibvirtExporter
+
+	// status is kept apart from mu, since the reload metrics are gathered
+	// during scrapes.
+	status         sync.Mutex
+	hash           float64
+	lastSuccessful bool
+	lastSuccess    time.Time
+}
+
+// NewReloader loads the configuration file at path, which may be empty for
+// none, and builds the exporter from it.
+func NewReloader(path string, build BuildFunc, logger log.Logger) (*Reloader, error) {
+	r := &Reloader{
+		path:   path,
+		build:  build,
+		logger: logger,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the configuration file again and swaps in a new exporter if
+// the file changed.
+func (r *Reloader) Reload() error {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	config := &Config{}
+	var err error
+	if r.path != "" {
+		config, err = LoadConfig(r.path)
+	}
+	// an unchanged file keeps the exporter, its connection and its counters
+	if err == nil && r.config != nil && config.hash == r.config.hash {
+		r.status.Lock()
+		r.lastSuccessful = true
+		r.lastSuccess = time.Now()
+		r.status.Unlock()
+		_ = level.Info(r.logger).Log("msg", "configuration unchanged", "file", r.path)
+		return nil
+	}
+	var exporter *LibvirtExporter
+	if err == nil {
+		exporter, err = r.build(config)
+	}
+
+	r.status.Lock()
+	r.lastSuccessful = err == nil
+	r.status.Unlock()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.exporter
+	r.config, r.exporter = config, exporter
+	r.mu.Unlock()
+
+	r.status.Lock()
+	// the leading 48 bits of the hash, which a float64 holds exactly
+	r.hash = float64(binary.BigEndian.Uint64(config.hash[:8]) >> 16)
+	r.lastSuccess = time.Now()
+	r.status.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			_ = level.Warn(r.logger).Log("warn", "failed to close the previous exporter", "msg", err)
+		}
+	}
+	_ = level.Info(r.logger).Log("msg", "loaded configuration", "file", r.path)
+	return nil
+}
+
+// Close closes the current exporter.
+func (r *Reloader) Close() error {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exporter.Close()
+}
+
+// Handler is LibvirtExporter.Handler for whichever exporter is current.
+func (r *Reloader) Handler(gatherer prometheus.Gatherer, timeoutOffset time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		r.exporter.Handler(gatherer, timeoutOffset).ServeHTTP(w, req)
+	})
+}
+
+// ProbeHandler is ProbeHandler with the modules of the current configuration.
+func (r *Reloader) ProbeHandler(timeoutOffset time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.RLock()
+		config := r.config
+		r.mu.RUnlock()
+		ProbeHandler(config, timeoutOffset, r.logger).ServeHTTP(w, req)
+	})
+}
+
+// ReloadHandler reloads the configuration on POST requests.
+func (r *Reloader) ReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "reload requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.Reload(); err != nil {
+			_ = level.Error(r.logger).Log("err", "failed to reload configuration", "msg", err)
+			http.Error(w, "failed to reload configuration: "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (r *Reloader) Describe(ch chan<- *prometheus.Desc) {
+	ch <- libvirtConfigHashDesc
+	ch <- libvirtConfigLastReloadSuccessfulDesc
+	ch <- libvirtConfigLastReloadSuccessTimestampDesc
+}
+
+func (r *Reloader) Collect(ch chan<- prometheus.Metric) {
+	r.status.Lock()
+	defer r.status.Unlock()
+
+	successful := 0.0
+	if r.lastSuccessful {
+		successful = 1
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtConfigHashDesc, prometheus.GaugeValue, r.hash)
+	ch <- prometheus.MustNewConstMetric(libvirtConfigLastReloadSuccessfulDesc, prometheus.GaugeValue, successful)
+	ch <- prometheus.MustNewConstMetric(libvirtConfigLastReloadSuccessTimestampDesc, prometheus.GaugeValue, float64(r.lastSuccess.UnixNano())/1e9)
+}
@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+libvirt:
+  uri: qemu+tcp://hv1/system
+  poll_interval: 30s
+  sample_interval: 10s
+  rate_windows: [2m]
+collectors:
+  storagepool: false
+  blockiotune: true
+`), 0o600))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	uri, driver := cfg.Target("/var/run/libvirt/libvirt-sock-ro", libvirt.QEMUSession)
+	assert.Equal(t, "qemu+tcp://hv1/system", uri)
+	assert.Equal(t, libvirt.QEMUSession, driver)
+
+	e := &LibvirtExporter{}
+	for _, opt := range cfg.Options() {
+		opt(e)
+	}
+	assert.Equal(t, 30*time.Second, e.pollInterval)
+	assert.Equal(t, 10*time.Second, e.sampleInterval)
+	assert.Equal(t, []time.Duration{2 * time.Minute}, e.rateWindows)
+	assert.Contains(t, e.collectorNames, "blockiotune")
+	assert.NotContains(t, e.collectorNames, "storagepool")
+
+	require.NoError(t, os.WriteFile(path, []byte("collectors:\n  bogus: true\n"), 0o600))
+	_, err = LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	build := func(config *Config) (*LibvirtExporter, error) {
+		uri, driver := config.Target(filepath.Join(dir, "missing-sock"), libvirt.QEMUSystem)
+		return NewLibvirtExporter(uri, driver, log.NewNopLogger(), config.Options()...)
+	}
+	gauge := func(r *Reloader, desc *prometheus.Desc) float64 {
+		ch := make(chan prometheus.Metric, 3)
+		r.Collect(ch)
+		close(ch)
+		for m := range ch {
+			if m.Desc() == desc {
+				var pb dto.Metric
+				require.NoError(t, m.Write(&pb))
+				return pb.GetGauge().GetValue()
+			}
+		}
+		t.Fatalf("%s not collected", desc)
+		return 0
+	}
+
+	write("collectors:\n  block: false\n")
+	r, err := NewReloader(path, build, log.NewNopLogger())
+	require.NoError(t, err)
+	defer r.Close()
+
+	first := r.exporter
+	assert.NotContains(t, first.collectorNames, "block")
+	assert.Equal(t, 1.0, gauge(r, libvirtConfigLastReloadSuccessfulDesc))
+	hash := gauge(r, libvirtConfigHashDesc)
+	assert.NotZero(t, hash)
+
+	// a broken file keeps the running exporter
+	write("collectors: [")
+	assert.Error(t, r.Reload())
+	assert.Same(t, first, r.exporter)
+	assert.Equal(t, 0.0, gauge(r, libvirtConfigLastReloadSuccessfulDesc))
+	assert.Equal(t, hash, gauge(r, libvirtConfigHashDesc))
+
+	// an unchanged file keeps the running exporter as well
+	write("collectors:\n  block: false\n")
+	require.NoError(t, r.Reload())
+	assert.Same(t, first, r.exporter)
+	assert.Equal(t, 1.0, gauge(r, libvirtConfigLastReloadSuccessfulDesc))
+	assert.Equal(t, hash, gauge(r, libvirtConfigHashDesc))
+
+	rec := httptest.NewRecorder()
+	r.ReloadHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	write("collectors:\n  block: true\n")
+	rec = httptest.NewRecorder()
+	r.ReloadHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotSame(t, first, r.exporter)
+	assert.Contains(t, r.exporter.collectorNames, "block")
+	assert.Equal(t, 1.0, gauge(r, libvirtConfigLastReloadSuccessfulDesc))
+	assert.NotEqual(t, hash, gauge(r, libvirtConfigHashDesc))
+}
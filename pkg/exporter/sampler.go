@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// sampledStats are the stats groups the utilization metrics are derived from.
+const sampledStats = libvirt.DomainStatsVCPU | libvirt.DomainStatsBlock
+
+// sampledBlockCounters are the block counters sampled for every disk.
+var sampledBlockCounters = []string{"rd.reqs", "wr.reqs", "rd.bytes", "wr.bytes"}
+
+// sampledVCPUCounters are the counters sampled for every vCPU.
+var sampledVCPUCounters = []string{"time", "delay"}
+
+// sampler records the counters of all domains into a rateTracker at a fixed
+// interval, independently of scrapes.
+type sampler struct {
+	conn   *libvirtConnection
+	rates  *rateTracker
+	logger log.Logger
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newSampler(conn *libvirtConnection, rates *rateTracker, logger log.Logger) *sampler {
+	return &sampler{
+		conn:   conn,
+		rates:  rates,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *sampler) start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *sampler) stop() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+func (s *sampler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.rates.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sample(); err != nil {
+			_ = level.Warn(s.logger).Log("warn", "failed to sample domain counters", "msg", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// sample fetches the counters of all domains with a single
+// ConnectGetAllDomainStats call.
+func (s *sampler) sample() error {
+	l, err := s.conn.get()
+	if err != nil {
+		return err
+	}
+	records, err := l.ConnectGetAllDomainStats(nil, uint32(sampledStats), 0)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	uuids := make([]libvirt.UUID, 0, len(records))
+	for _, record := range records {
+		uuids = append(uuids, record.Dom.UUID)
+		s.record(record.Dom.UUID, newDomainStats(record.Params), now)
+	}
+	s.rates.retain(uuids)
+	return nil
+}
+
+func (s *sampler) record(uuid libvirt.UUID, stats *domainStats, now time.Time) {
+	for device, idx := range stats.indexByName("block") {
+		for _, counter := range sampledBlockCounters {
+			if v, ok := stats.uint("block." + idx + "." + counter); ok {
+				s.rates.add(rateKey{uuid: uuid, device: device, counter: counter}, float64(v), now)
+			}
+		}
+	}
+
+	for _, param := range stats.params {
+		// vcpu.<n>.<counter>
+		parts := strings.Split(param.Field, ".")
+		if len(parts) != 3 || parts[0] != "vcpu" {
+			continue
+		}
+		if _, err := strconv.Atoi(parts[1]); err != nil {
+			continue
+		}
+		for _, counter := range sampledVCPUCounters {
+			if parts[2] != counter {
+				continue
+			}
+			if v, ok := stats.uint(param.Field); ok {
+				s.rates.add(rateKey{uuid: uuid, device: parts[1], counter: counter}, float64(v), now)
+			}
+		}
+	}
+}
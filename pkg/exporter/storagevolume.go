@@ -0,0 +1,117 @@
+package exporter
+
+import (
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	libvirtStoragePoolVolumesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage_pool", "volumes"),
+		"Number of volumes in the active storage pool.",
+		[]string{"storage_pool"},
+		nil)
+	libvirtStorageVolumeInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage_volume", "info"),
+		"Type and path of the storage volume as labels, the path as source_file to be joined with libvirt_domain_block_stats_info.",
+		[]string{"storage_pool", "volume", "type", "source_file"},
+		nil)
+	libvirtStorageVolumeCapacityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage_volume", "capacity_bytes"),
+		"Logical size of the storage volume in bytes.",
+		[]string{"storage_pool", "volume"},
+		nil)
+	libvirtStorageVolumeAllocationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "storage_volume", "allocation_bytes"),
+		"Bytes of the storage pool allocated to the storage volume.",
+		[]string{"storage_pool", "volume"},
+		nil)
+
+	storageVolumeTypes = map[libvirt.StorageVolType]string{
+		libvirt.StorageVolFile:    "file",
+		libvirt.StorageVolBlock:   "block",
+		libvirt.StorageVolDir:     "dir",
+		libvirt.StorageVolNetwork: "network",
+		libvirt.StorageVolNetdir:  "netdir",
+		libvirt.StorageVolPloop:   "ploop",
+	}
+)
+
+func init() {
+	registerCollector(&collector{
+		name: "storagevolume",
+		descs: []*prometheus.Desc{
+			libvirtStoragePoolVolumesDesc,
+			libvirtStorageVolumeInfoDesc,
+			libvirtStorageVolumeCapacityDesc,
+			libvirtStorageVolumeAllocationDesc,
+		},
+		host: CollectStorageVolumes,
+	}, false)
+}
+
+// CollectStorageVolumes collects the volumes of the active storage pools.
+func CollectStorageVolumes(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error) {
+	var pools []libvirt.StoragePool
+	if pools, _, err = l.ConnectListAllStoragePools(1, libvirt.ConnectListStoragePoolsActive); err != nil {
+		_ = level.Error(logger).Log("err", "failed to collect storage pools", "msg", err)
+		return err
+	}
+	// one failing pool does not hide the others
+	for _, pool := range pools {
+		if poolErr := CollectStoragePoolVolumes(ch, l, pool, logger); isLibvirtErr(poolErr, libvirt.ErrNoStoragePool, libvirt.ErrOperationInvalid) {
+			// undefined (no storage pool) or stopped (operation invalid) since it
+			// was listed
+			continue
+		} else if poolErr != nil {
+			_ = level.Error(logger).Log("err", "failed to collect storage volumes", "pool", pool.Name, "msg", poolErr)
+			err = poolErr
+		}
+	}
+	return err
+}
+
+func CollectStoragePoolVolumes(ch chan<- prometheus.Metric, l *libvirt.Libvirt, pool libvirt.StoragePool, logger log.Logger) (err error) {
+	var vols []libvirt.StorageVol
+	if vols, _, err = l.StoragePoolListAllVolumes(pool, 1, 0); err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtStoragePoolVolumesDesc, prometheus.GaugeValue, float64(len(vols)), pool.Name)
+
+	// one failing volume does not hide the others
+	for _, vol := range vols {
+		volType, capacity, allocation, volErr := l.StorageVolGetInfo(vol)
+		if isLibvirtErr(volErr, libvirt.ErrNoStorageVol) {
+			// deleted since it was listed
+			continue
+		} else if volErr != nil {
+			_ = level.Warn(logger).Log("warn", "failed to get StorageVolGetInfo", "pool", pool.Name, "volume", vol.Name, "msg", volErr)
+			err = volErr
+			continue
+		}
+		path, volErr := l.StorageVolGetPath(vol)
+		if isLibvirtErr(volErr, libvirt.ErrNoStorageVol) {
+			continue
+		} else if volErr != nil {
+			_ = level.Warn(logger).Log("warn", "failed to get StorageVolGetPath", "pool", pool.Name, "volume", vol.Name, "msg", volErr)
+			err = volErr
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(libvirtStorageVolumeInfoDesc, prometheus.GaugeValue, 1,
+			pool.Name, vol.Name, storageVolumeType(volType), path)
+		ch <- prometheus.MustNewConstMetric(libvirtStorageVolumeCapacityDesc, prometheus.GaugeValue, float64(capacity), pool.Name, vol.Name)
+		ch <- prometheus.MustNewConstMetric(libvirtStorageVolumeAllocationDesc, prometheus.GaugeValue, float64(allocation), pool.Name, vol.Name)
+	}
+	return err
+}
+
+// storageVolumeType returns the name of the type StorageVolGetInfo returns.
+func storageVolumeType(volType int8) string {
+	if name, ok := storageVolumeTypes[libvirt.StorageVolType(volType)]; ok {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thongth1998/libvirt-exporter/libvirt_schema"
+)
+
+func TestStorageVolumeType(t *testing.T) {
+	assert.Equal(t, "file", storageVolumeType(int8(libvirt.StorageVolFile)))
+	assert.Equal(t, "block", storageVolumeType(int8(libvirt.StorageVolBlock)))
+	assert.Equal(t, "ploop", storageVolumeType(int8(libvirt.StorageVolPloop)))
+	assert.Equal(t, "unknown", storageVolumeType(42))
+}
+
+// TestStorageVolumeJoin checks that source_file of block devices matches the
+// paths StorageVolGetPath returns for the volumes of file and LVM pools.
+func TestStorageVolumeJoin(t *testing.T) {
+	var domain libvirt_schema.Domain
+	require.NoError(t, xml.Unmarshal([]byte(`<domain type='kvm'>
+  <devices>
+    <disk type='file' device='disk'>
+      <source file='/var/lib/libvirt/images/web-1.qcow2'/>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+    <disk type='block' device='disk'>
+      <source dev='/dev/vg0/web-1-data'/>
+      <target dev='vdb' bus='virtio'/>
+    </disk>
+    <disk type='volume' device='disk'>
+      <source pool='thinpool' volume='web-1-scratch'/>
+      <target dev='vdc' bus='virtio'/>
+    </disk>
+  </devices>
+</domain>`), &domain))
+	require.Len(t, domain.Devices.Disks, 3)
+
+	assert.Equal(t, "/var/lib/libvirt/images/web-1.qcow2", domain.Devices.Disks[0].Source.Path())
+	assert.Equal(t, "/dev/vg0/web-1-data", domain.Devices.Disks[1].Source.Path())
+	// volume disks have no path in the domain XML
+	assert.Empty(t, domain.Devices.Disks[2].Source.Path())
+}
@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var libvirtVersionInfoDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("libvirt", "", "version_info"),
+	"Versions of libvirt and the hypervisor, and the hostname of the host as labels.",
+	[]string{"hypervisor_type", "hypervisor_version", "libvirt_version", "hostname"},
+	nil)
+
+func init() {
+	registerCollector(&collector{
+		name:  "version",
+		descs: []*prometheus.Desc{libvirtVersionInfoDesc},
+		host:  CollectVersion,
+	}, true)
+}
+
+// CollectVersion collects libvirt_version_info.
+func CollectVersion(ch chan<- prometheus.Metric, l *libvirt.Libvirt, logger log.Logger) (err error) {
+	hvType, err := l.ConnectGetType()
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get ConnectGetType", "msg", err)
+		return err
+	}
+	hvVersion, err := l.ConnectGetVersion()
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get ConnectGetVersion", "msg", err)
+		return err
+	}
+	libVersion, err := l.ConnectGetLibVersion()
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get ConnectGetLibVersion", "msg", err)
+		return err
+	}
+	hostname, err := l.ConnectGetHostname()
+	if err != nil {
+		_ = level.Warn(logger).Log("warn", "failed to get ConnectGetHostname", "msg", err)
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(libvirtVersionInfoDesc, prometheus.GaugeValue, 1,
+		hvType, formatVersion(hvVersion), formatVersion(libVersion), hostname)
+	return nil
+}
+
+// formatVersion formats a version libvirt encodes as
+// major * 1,000,000 + minor * 1,000 + release.
+func formatVersion(v uint64) string {
+	return fmt.Sprintf("%d.%d.%d", v/1000000, v/1000%1000, v%1000)
+}
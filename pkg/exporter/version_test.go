@@ -0,0 +1,13 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatVersion(t *testing.T) {
+	assert.Equal(t, "10.0.0", formatVersion(10000000))
+	assert.Equal(t, "8.2.1", formatVersion(8002001))
+	assert.Equal(t, "0.9.12", formatVersion(9012))
+}
@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultWorkers = 4
+
+var (
+	libvirtDomainsTimedOutDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "", "domains_timed_out_total"),
+		"Number of domains abandoned because they were not collected before the scrape deadline.",
+		nil,
+		nil)
+	libvirtDomainErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("libvirt_exporter", "", "domain_errors_total"),
+		"Number of domains left out of a scrape because collecting them failed.",
+		nil,
+		nil)
+)
+
+// domainResult holds the metrics collected for a single domain.
+type domainResult struct {
+	domain  domainMeta
+	metrics []prometheus.Metric
+	err     error
+}
+
+// collectDomains collects all domains with the given collectors on a pool of
+// e.workers goroutines.
+// Metrics are buffered per domain and only forwarded to ch once the domain is
+// complete, so domains that are still running when ctx expires can be
+// abandoned without writing to ch after Collect returned. A domain that fails
+// is counted and skipped, and one that disappeared in the meantime is skipped
+// silently.
+func (e *LibvirtExporter) collectDomains(ctx context.Context, ch chan<- prometheus.Metric, l *libvirt.Libvirt, domains []domainMeta, collectors []*collector) error {
+	if len(domains) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan domainMeta)
+	// buffered for every domain, so abandoned workers never block
+	results := make(chan domainResult, len(domains))
+
+	for i := 0; i < min(e.workers, len(domains)); i++ {
+		go func() {
+			for domain := range jobs {
+				metrics, err := collectToSlice(func(ch chan<- prometheus.Metric) error {
+					return CollectDomain(ch, l, domain, e.labels, collectors, e.logger)
+				})
+				results <- domainResult{domain: domain, metrics: metrics, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, domain := range domains {
+			select {
+			case jobs <- domain:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for done := 0; done < len(domains); done++ {
+		select {
+		case res := <-results:
+			if libvirt.IsNotFound(res.err) {
+				_ = level.Debug(e.logger).Log("msg", "domain disappeared during collection", "domain", res.domain.domainName)
+				continue
+			}
+			for _, m := range res.metrics {
+				ch <- m
+			}
+			if res.err != nil {
+				e.errors.add(res.err)
+				e.domainErrors.Add(1)
+				_ = level.Error(e.logger).Log("err", "failed to collect domain", "domain", res.domain.domainName, "msg", res.err)
+			}
+		case <-ctx.Done():
+			timedOut := len(domains) - done
+			e.domainsTimedOut.Add(uint64(timedOut))
+			return fmt.Errorf("scrape deadline exceeded, abandoned %d of %d domains: %w", timedOut, len(domains), ctx.Err())
+		}
+	}
+	return nil
+}
+
+// collectToSlice runs fn and returns the metrics it sent.
+func collectToSlice(fn func(ch chan<- prometheus.Metric) error) ([]prometheus.Metric, error) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		defer close(done)
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+	}()
+
+	err := fn(ch)
+	close(ch)
+	<-done
+	return metrics, err
+}
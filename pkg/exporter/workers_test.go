@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectDomains(t *testing.T) {
+	running := newDomainStats([]libvirt.TypedParam{
+		{Field: "state.state", Value: libvirt.TypedParamValue{D: 1, I: int32(1)}},
+	})
+	domains := []domainMeta{
+		{domainName: "ok", stats: running},
+		{domainName: "gone", stats: running},
+		{domainName: "broken", stats: running},
+		// without bulk stats the domain is queried over the connection,
+		// which was never established
+		{domainName: "unreachable"},
+	}
+	collectors := []*collector{{
+		name: "test",
+		domain: func(ch chan<- prometheus.Metric, _ *libvirt.Libvirt, domain domainMeta, promLabels domainLabels, _ log.Logger) error {
+			switch domain.domainName {
+			case "gone":
+				return libvirt.Error{Code: uint32(libvirt.ErrNoDomain)}
+			case "broken":
+				return errors.New("broken")
+			}
+			return nil
+		},
+	}}
+
+	labels, err := newLabelSchema(DefaultLabelConfig)
+	require.NoError(t, err)
+	e := &LibvirtExporter{workers: 2, labels: labels, logger: log.NewNopLogger()}
+	metrics, err := collectToSlice(func(ch chan<- prometheus.Metric) error {
+		return e.collectDomains(context.Background(), ch, libvirt.NewWithDialer(&failingDialer{}), domains, collectors)
+	})
+	require.NoError(t, err)
+
+	// a failing collector only loses its own metrics, a vanished domain
+	// is dropped entirely
+	byDomain := map[string]int{}
+	for _, m := range metrics {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		for _, lp := range pb.GetLabel() {
+			if lp.GetName() == "domain" {
+				byDomain[lp.GetValue()]++
+			}
+		}
+	}
+	assert.NotZero(t, byDomain["ok"])
+	assert.NotZero(t, byDomain["broken"])
+	assert.Zero(t, byDomain["gone"])
+	assert.Zero(t, byDomain["unreachable"])
+	// only the domain that failed as a whole counts as a domain error,
+	// collector errors are reported per collector and a vanished domain
+	// is no error at all
+	assert.EqualValues(t, 1, e.domainErrors.Load())
+}